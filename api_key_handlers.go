@@ -0,0 +1,194 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NewApiKey is returned once when a key is created or rotated. The key
+// cannot be retrieved later.
+type NewApiKey struct {
+	ApiKey ApiKey `json:"apiKey"`
+	Key    string `json:"key"`
+}
+
+// @Summary Get API keys
+// @Description Retrieves all API keys of other backends, newest first, with when they were last used
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param X-Api-Key header string true "API key with the api-keys:manage scope, or the admin key"
+// @Success 200 {array} ApiKey "List of API keys"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api-keys [get]
+func getApiKeys(c *gin.Context, repo *ApiKeyRepository) {
+	apiKeys, err := repo.GetAllApiKeys(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, apiKeys)
+}
+
+// @Summary Create an API key
+// @Description Creates an API key with the given scopes for another backend. The key is only returned in this response and is sent in the X-Api-Key header. The first keys are created with the admin key from ADMIN_API_KEY.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param X-Api-Key header string true "API key with the api-keys:manage scope, or the admin key"
+// @Param name body string true "What the key is for"
+// @Param scopes body []string true "Permissions, e.g. tokens:grant or benefits:write"
+// @Param expiresAt body string false "When the key stops working"
+// @Success 201 {object} NewApiKey "API key with its key"
+// @Failure 400 {object} ErrorResponse "Invalid name, scopes or expiry"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api-keys [post]
+func createApiKey(c *gin.Context, repo *ApiKeyRepository) {
+	var req struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expiresAt"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	apiKey := ApiKey{
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		CreatedAt: now,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := apiKey.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if apiKey.ExpiresAt != nil && !apiKey.ExpiresAt.After(now) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expiresAt must be in the future"})
+		return
+	}
+
+	key, keyHash, err := generateKey(apiKeyPrefix)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	apiKey.KeyPrefix = key[:len(apiKeyPrefix)+apiKeyShownChars]
+	apiKey.KeyHash = keyHash
+
+	savedApiKey, err := repo.AddApiKey(c.Request.Context(), &apiKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// Key hashes are never serialized, so keys stay out of the audit log
+	recordAuditChange(c, "api-keys", savedApiKey.Id, nil, savedApiKey)
+	c.JSON(http.StatusCreated, NewApiKey{ApiKey: *savedApiKey, Key: key})
+}
+
+// @Summary Rotate an API key
+// @Description Issues a new key for an API key. The previous key keeps working for 24 hours so the backend using it can switch over.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param X-Api-Key header string true "API key with the api-keys:manage scope, or the admin key"
+// @Param id path string true "API key ID"
+// @Success 200 {object} NewApiKey "API key with its new key"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 404 {object} ErrorResponse "API key not found or revoked"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api-keys/{id}/rotate [post]
+func rotateApiKey(c *gin.Context, repo *ApiKeyRepository) {
+	apiKeyId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	existingApiKey, err := repo.GetApiKeyByID(c.Request.Context(), apiKeyId)
+	if err != nil {
+		if err == ErrApiKeyNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, keyHash, err := generateKey(apiKeyPrefix)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	now := time.Now()
+	apiKey, err := repo.RotateApiKey(c.Request.Context(), apiKeyId, key[:len(apiKeyPrefix)+apiKeyShownChars], keyHash, now, now.Add(apiKeyRotation))
+	if err != nil {
+		if err == ErrApiKeyNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAuditChange(c, "api-keys", apiKeyId, existingApiKey, apiKey)
+	c.JSON(http.StatusOK, NewApiKey{ApiKey: *apiKey, Key: key})
+}
+
+// @Summary Revoke an API key
+// @Description Revokes an API key, including its previous key after a rotation, so it can no longer be used
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param X-Api-Key header string true "API key with the api-keys:manage scope, or the admin key"
+// @Param id path string true "API key ID"
+// @Success 200 {object} object "API key revoked successfully"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 404 {object} ErrorResponse "API key not found or already revoked"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api-keys/{id} [delete]
+func revokeApiKey(c *gin.Context, repo *ApiKeyRepository) {
+	apiKeyId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	existingApiKey, err := repo.GetApiKeyByID(c.Request.Context(), apiKeyId)
+	if err != nil {
+		if err == ErrApiKeyNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := repo.RevokeApiKey(c.Request.Context(), apiKeyId, time.Now()); err != nil {
+		if err == ErrApiKeyNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	revokedApiKey, err := repo.GetApiKeyByID(c.Request.Context(), apiKeyId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAuditChange(c, "api-keys", apiKeyId, existingApiKey, revokedApiKey)
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+}
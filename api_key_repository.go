@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrApiKeyNotFound = errors.New("API key not found")
+var ErrInvalidApiKey = errors.New("invalid API key")
+
+const (
+	ScopeTokensGrant      = "tokens:grant"
+	ScopeBenefitsWrite    = "benefits:write"
+	ScopeActionsWrite     = "actions:write"
+	ScopeWalletsRead      = "wallets:read"
+	ScopeWalletsWrite     = "wallets:write"
+	ScopeAuditRead        = "audit:read"
+	ScopeApiKeysManage    = "api-keys:manage"
+	ScopePartnersManage   = "partners:manage"
+	ScopeSettlementsWrite = "settlements:write"
+	ScopePromoCodesWrite  = "promo-codes:write"
+	ScopeRewardsWrite     = "rewards:write"
+	ScopeWebhooksManage   = "webhooks:manage"
+)
+
+var apiKeyScopes = []string{
+	ScopeTokensGrant, ScopeBenefitsWrite, ScopeActionsWrite, ScopeWalletsRead, ScopeWalletsWrite, ScopeAuditRead, ScopeApiKeysManage,
+	ScopePartnersManage, ScopeSettlementsWrite, ScopePromoCodesWrite, ScopeRewardsWrite, ScopeWebhooksManage,
+}
+
+// ApiKey lets another city backend call the service. Only hashes of keys
+// are stored. After a rotation the previous key keeps working until
+// PreviousKeyExpiresAt so callers can switch without downtime.
+type ApiKey struct {
+	Id                   primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name                 string             `json:"name" bson:"name"`
+	Scopes               []string           `json:"scopes" bson:"scopes"`
+	KeyPrefix            string             `json:"keyPrefix" bson:"keyPrefix"`
+	KeyHash              string             `json:"-" bson:"keyHash"`
+	PreviousKeyHash      string             `json:"-" bson:"previousKeyHash,omitempty"`
+	PreviousKeyExpiresAt *time.Time         `json:"previousKeyExpiresAt,omitempty" bson:"previousKeyExpiresAt,omitempty"`
+	CreatedAt            time.Time          `json:"createdAt" bson:"createdAt"`
+	ExpiresAt            *time.Time         `json:"expiresAt,omitempty" bson:"expiresAt,omitempty"`
+	RotatedAt            *time.Time         `json:"rotatedAt,omitempty" bson:"rotatedAt,omitempty"`
+	RevokedAt            *time.Time         `json:"revokedAt,omitempty" bson:"revokedAt,omitempty"`
+	LastUsedAt           *time.Time         `json:"lastUsedAt,omitempty" bson:"lastUsedAt,omitempty"`
+	LastUsedIP           string             `json:"lastUsedIp,omitempty" bson:"lastUsedIp,omitempty"`
+}
+
+func (k *ApiKey) Validate() error {
+	if k.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidApiKey)
+	}
+	if len(k.Scopes) == 0 {
+		return fmt.Errorf("%w: at least one scope is required", ErrInvalidApiKey)
+	}
+	for _, scope := range k.Scopes {
+		if !slices.Contains(apiKeyScopes, scope) {
+			return fmt.Errorf("%w: unknown scope %q", ErrInvalidApiKey, scope)
+		}
+	}
+	return nil
+}
+
+func (k *ApiKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+type ApiKeyRepository struct {
+	collection *mongo.Collection
+}
+
+func NewApiKeyRepository(collection *mongo.Collection) (*ApiKeyRepository, error) {
+	_, err := collection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "keyHash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "previousKeyHash", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ApiKeyRepository{
+		collection: collection,
+	}, nil
+}
+
+func (r *ApiKeyRepository) GetAllApiKeys(ctx context.Context) ([]ApiKey, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	apiKeys := []ApiKey{}
+	if err := cursor.All(ctx, &apiKeys); err != nil {
+		return nil, err
+	}
+	return apiKeys, nil
+}
+
+func (r *ApiKeyRepository) AddApiKey(ctx context.Context, apiKey *ApiKey) (*ApiKey, error) {
+	result, err := r.collection.InsertOne(ctx, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	generatedID, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return nil, errors.New("failed to convert InsertedID to ObjectID")
+	}
+	apiKey.Id = generatedID
+	return apiKey, nil
+}
+
+// GetActiveApiKeyByHash finds a key that is neither revoked nor expired,
+// also by the hash of its previous key during the rotation grace period.
+func (r *ApiKeyRepository) GetActiveApiKeyByHash(ctx context.Context, keyHash string, now time.Time) (*ApiKey, error) {
+	filter := bson.M{
+		"revokedAt": bson.M{"$exists": false},
+		"$and": bson.A{
+			bson.M{"$or": bson.A{
+				bson.M{"keyHash": keyHash},
+				bson.M{"previousKeyHash": keyHash, "previousKeyExpiresAt": bson.M{"$gt": now}},
+			}},
+			bson.M{"$or": bson.A{
+				bson.M{"expiresAt": bson.M{"$exists": false}},
+				bson.M{"expiresAt": bson.M{"$gt": now}},
+			}},
+		},
+	}
+	var apiKey ApiKey
+	err := r.collection.FindOne(ctx, filter).Decode(&apiKey)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrApiKeyNotFound
+		}
+		return nil, err
+	}
+
+	return &apiKey, nil
+}
+
+func (r *ApiKeyRepository) GetApiKeyByID(ctx context.Context, id primitive.ObjectID) (*ApiKey, error) {
+	var apiKey ApiKey
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&apiKey)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrApiKeyNotFound
+		}
+		return nil, err
+	}
+
+	return &apiKey, nil
+}
+
+// RotateApiKey replaces the key's hash, keeping the current one valid
+// until graceUntil.
+func (r *ApiKeyRepository) RotateApiKey(ctx context.Context, id primitive.ObjectID, keyPrefix string, keyHash string, now time.Time, graceUntil time.Time) (*ApiKey, error) {
+	filter := bson.M{"_id": id, "revokedAt": bson.M{"$exists": false}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"previousKeyHash":      "$keyHash",
+			"previousKeyExpiresAt": graceUntil,
+			"keyHash":              keyHash,
+			"keyPrefix":            keyPrefix,
+			"rotatedAt":            now,
+		}}},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var apiKey ApiKey
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&apiKey)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrApiKeyNotFound
+		}
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+func (r *ApiKeyRepository) RevokeApiKey(ctx context.Context, id primitive.ObjectID, now time.Time) error {
+	filter := bson.M{"_id": id, "revokedAt": bson.M{"$exists": false}}
+	update := bson.M{
+		"$set":   bson.M{"revokedAt": now},
+		"$unset": bson.M{"previousKeyHash": "", "previousKeyExpiresAt": ""},
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrApiKeyNotFound
+	}
+	return nil
+}
+
+// MarkUsed records when and from where the key was last used.
+func (r *ApiKeyRepository) MarkUsed(ctx context.Context, id primitive.ObjectID, ip string, now time.Time) error {
+	update := bson.M{"$set": bson.M{"lastUsedAt": now, "lastUsedIp": ip}}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
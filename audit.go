@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	requestIdHeader       = "X-Request-Id"
+	requestIdContextKey   = "requestId"
+	actorHeader           = "X-Actor"
+	actorContextKey       = "actor"
+	auditChangeContextKey = "auditChanges"
+	auditWriteTimeout     = 5 * time.Second
+)
+
+// requestID takes the caller's request ID or makes one up, and echoes it
+// in the response so logs on both sides can be matched.
+func requestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIdHeader)
+		if id == "" || len(id) > 128 {
+			raw := make([]byte, 16)
+			if _, err := rand.Read(raw); err == nil {
+				id = hex.EncodeToString(raw)
+			}
+		}
+		c.Set(requestIdContextKey, id)
+		c.Header(requestIdHeader, id)
+		c.Next()
+	}
+}
+
+// auditActor names who made the request: an actor set by authentication,
+// the authenticated partner, or "anonymous".
+func auditActor(c *gin.Context) string {
+	if actor := c.GetString(actorContextKey); actor != "" {
+		return actor
+	}
+	if partnerId, ok := c.Get(partnerIdContextKey); ok {
+		return "partner:" + partnerId.(primitive.ObjectID).Hex()
+	}
+	return "anonymous"
+}
+
+// auditOnBehalfOf names the person an authenticated caller, such as an
+// admin panel, acted for in the X-Actor header. Anyone could send the
+// header, so it is ignored on unauthenticated requests.
+func auditOnBehalfOf(c *gin.Context) string {
+	_, partner := c.Get(partnerIdContextKey)
+	onBehalfOf := c.GetHeader(actorHeader)
+	if (c.GetString(actorContextKey) == "" && !partner) || len(onBehalfOf) > 128 {
+		return ""
+	}
+	return onBehalfOf
+}
+
+type auditChange struct {
+	targetType string
+	targetId   string
+	before     any
+	after      any
+}
+
+// recordAuditChange tells the audit log what a handler changed. Before is
+// nil for created and after nil for deleted documents. Handlers changing
+// several documents call it once for each.
+func recordAuditChange(c *gin.Context, targetType string, targetId primitive.ObjectID, before any, after any) {
+	recordAuditChangeOf(c, targetType, targetId.Hex(), before, after)
+}
+
+// recordAuditChangeOf is recordAuditChange for documents whose ID is not an
+// ObjectID, such as singleton settings.
+func recordAuditChangeOf(c *gin.Context, targetType string, targetId string, before any, after any) {
+	var changes []*auditChange
+	if value, ok := c.Get(auditChangeContextKey); ok {
+		changes = value.([]*auditChange)
+	}
+	c.Set(auditChangeContextKey, append(changes, &auditChange{
+		targetType: targetType,
+		targetId:   targetId,
+		before:     before,
+		after:      after,
+	}))
+}
+
+// auditLog records every successful mutating request once the handler is
+// done. Handlers that call recordAuditChange also get the changed fields,
+// in one entry per changed document.
+func auditLog(repo *AuditRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return
+		}
+		if c.Writer.Status() >= http.StatusBadRequest || c.FullPath() == "" {
+			return
+		}
+
+		entry := AuditEntry{
+			Timestamp:  time.Now(),
+			RequestId:  c.GetString(requestIdContextKey),
+			Actor:      auditActor(c),
+			OnBehalfOf: auditOnBehalfOf(c),
+			Action:     c.Request.Method + " " + c.FullPath(),
+			Path:       c.Request.URL.Path,
+			Status:     c.Writer.Status(),
+			ClientIP:   c.ClientIP(),
+			TargetType: strings.Split(strings.TrimPrefix(c.FullPath(), "/"), "/")[0],
+		}
+		if len(c.Params) > 0 {
+			entry.TargetId = c.Params[0].Value
+		}
+		entries := []AuditEntry{entry}
+		if value, ok := c.Get(auditChangeContextKey); ok {
+			entries = entries[:0]
+			for _, change := range value.([]*auditChange) {
+				changed := entry
+				changed.TargetType = change.targetType
+				changed.TargetId = change.targetId
+				changes, err := diffDocuments(change.before, change.after)
+				if err != nil {
+					log.Printf("audit: failed to diff %s %s: %v", change.targetType, change.targetId, err)
+				}
+				changed.Changes = changes
+				entries = append(entries, changed)
+			}
+		}
+
+		// The response is already sent, so a cancelled request must not
+		// lose the entries.
+		ctx, cancel := context.WithTimeout(context.Background(), auditWriteTimeout)
+		defer cancel()
+		for _, entry := range entries {
+			if _, err := repo.AddEntry(ctx, &entry); err != nil {
+				log.Printf("audit: failed to record %s by %s: %v", entry.Action, entry.Actor, err)
+			}
+		}
+	}
+}
+
+// diffDocuments compares the top-level fields of two documents as they
+// appear in the API and returns the ones that differ.
+func diffDocuments(before any, after any) (map[string]AuditChange, error) {
+	beforeFields, err := documentFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := documentFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]AuditChange{}
+	for field, value := range beforeFields {
+		if !reflect.DeepEqual(value, afterFields[field]) {
+			changes[field] = AuditChange{Before: value, After: afterFields[field]}
+		}
+	}
+	for field, value := range afterFields {
+		if _, found := beforeFields[field]; !found {
+			changes[field] = AuditChange{After: value}
+		}
+	}
+	return changes, nil
+}
+
+func documentFields(document any) (map[string]any, error) {
+	fields := map[string]any{}
+	if value := reflect.ValueOf(document); !value.IsValid() || (value.Kind() == reflect.Pointer && value.IsNil()) {
+		return fields, nil
+	}
+	data, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultAuditLogLimit = 50
+	maxAuditLogLimit     = 500
+)
+
+// @Summary Get the audit log
+// @Description Retrieves recorded changes, newest first. The log is read-only; entries are written by the service for every successful mutating request.
+// @Tags audit
+// @Accept json
+// @Produce json
+// @Param actor query string false "Who made the change"
+// @Param action query string false "Method and route, e.g. PUT /benefits/:id"
+// @Param target_type query string false "Kind of changed document, e.g. benefits"
+// @Param target_id query string false "ID of the changed document"
+// @Param request_id query string false "Request ID"
+// @Param from query string false "Changes at or after this time (RFC 3339)"
+// @Param to query string false "Changes before this time (RFC 3339)"
+// @Param limit query int false "Number of entries, at most 500" default(50)
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 200 {array} AuditEntry "Audit log entries"
+// @Failure 400 {object} ErrorResponse "Invalid time or limit"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /audit-log [get]
+func getAuditLog(c *gin.Context, repo *AuditRepository) {
+	filter := AuditFilter{
+		Actor:      c.Query("actor"),
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetId:   c.Query("target_id"),
+		RequestId:  c.Query("request_id"),
+	}
+	for name, bound := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := c.Query(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+				return
+			}
+			*bound = t
+		}
+	}
+	limit := defaultAuditLogLimit
+	if limitParam := c.Query("limit"); limitParam != "" {
+		var err error
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit <= 0 || limit > maxAuditLogLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+	}
+
+	entries, err := repo.GetEntries(c.Request.Context(), filter, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuditChange is the value of a field before and after a change; a nil
+// side means the field did not exist.
+type AuditChange struct {
+	Before any `json:"before" bson:"before"`
+	After  any `json:"after" bson:"after"`
+}
+
+// AuditEntry records a successful mutating request. Action is the method
+// and route, e.g. "PUT /benefits/:id".
+type AuditEntry struct {
+	Id         primitive.ObjectID     `json:"id" bson:"_id,omitempty"`
+	Timestamp  time.Time              `json:"timestamp" bson:"timestamp"`
+	RequestId  string                 `json:"requestId" bson:"requestId"`
+	Actor      string                 `json:"actor" bson:"actor"`
+	OnBehalfOf string                 `json:"onBehalfOf,omitempty" bson:"onBehalfOf,omitempty"`
+	Action     string                 `json:"action" bson:"action"`
+	Path       string                 `json:"path" bson:"path"`
+	Status     int                    `json:"status" bson:"status"`
+	ClientIP   string                 `json:"clientIp" bson:"clientIp"`
+	TargetType string                 `json:"targetType" bson:"targetType"`
+	TargetId   string                 `json:"targetId,omitempty" bson:"targetId,omitempty"`
+	Changes    map[string]AuditChange `json:"changes,omitempty" bson:"changes,omitempty"`
+}
+
+// AuditFilter narrows an audit log query; empty fields match everything.
+type AuditFilter struct {
+	Actor      string
+	Action     string
+	TargetType string
+	TargetId   string
+	RequestId  string
+	From       time.Time
+	To         time.Time
+}
+
+// AuditRepository only ever inserts and reads entries so the log cannot be
+// altered through the service.
+type AuditRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAuditRepository(collection *mongo.Collection) (*AuditRepository, error) {
+	_, err := collection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "timestamp", Value: -1}}},
+		{Keys: bson.D{{Key: "actor", Value: 1}, {Key: "timestamp", Value: -1}}},
+		{Keys: bson.D{{Key: "targetType", Value: 1}, {Key: "targetId", Value: 1}, {Key: "timestamp", Value: -1}}},
+		{Keys: bson.D{{Key: "requestId", Value: 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuditRepository{
+		collection: collection,
+	}, nil
+}
+
+func (r *AuditRepository) AddEntry(ctx context.Context, entry *AuditEntry) (*AuditEntry, error) {
+	result, err := r.collection.InsertOne(ctx, entry)
+	if err != nil {
+		return nil, err
+	}
+	generatedID, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return nil, errors.New("failed to convert InsertedID to ObjectID")
+	}
+	entry.Id = generatedID
+	return entry, nil
+}
+
+// GetEntries returns the newest entries matching the filter.
+func (r *AuditRepository) GetEntries(ctx context.Context, filter AuditFilter, limit int) ([]AuditEntry, error) {
+	query := bson.M{}
+	for field, value := range map[string]string{
+		"actor":      filter.Actor,
+		"action":     filter.Action,
+		"targetType": filter.TargetType,
+		"targetId":   filter.TargetId,
+		"requestId":  filter.RequestId,
+	} {
+		if value != "" {
+			query[field] = value
+		}
+	}
+	timestamp := bson.M{}
+	if !filter.From.IsZero() {
+		timestamp["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		timestamp["$lt"] = filter.To
+	}
+	if len(timestamp) > 0 {
+		query["timestamp"] = timestamp
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []AuditEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const clockLayout = "15:04"
+
+// cityLocation is the time zone of the city. Calendar days and weeks,
+// availability windows and leaderboard periods follow it. It is set from
+// CITY_TIMEZONE at startup.
+var cityLocation = time.Local
+
+var ErrInvalidAvailabilityWindow = errors.New("invalid availability window")
+
+// AvailabilityWindow is a recurring daily time range, e.g. weekdays from
+// 10:00 to 14:00. Times are in the city's time zone and an empty
+// Weekdays list means every day.
+type AvailabilityWindow struct {
+	Weekdays []time.Weekday `json:"weekdays" bson:"weekdays" swaggertype:"array,integer"`
+	Start    string         `json:"start" bson:"start" example:"10:00"`
+	End      string         `json:"end" bson:"end" example:"14:00"`
+}
+
+func (w AvailabilityWindow) bounds() (time.Duration, time.Duration, error) {
+	start, err := time.Parse(clockLayout, w.Start)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: start %q", ErrInvalidAvailabilityWindow, w.Start)
+	}
+	end, err := time.Parse(clockLayout, w.End)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: end %q", ErrInvalidAvailabilityWindow, w.End)
+	}
+	if !end.After(start) {
+		return 0, 0, fmt.Errorf("%w: end must be after start", ErrInvalidAvailabilityWindow)
+	}
+	midnight, _ := time.Parse(clockLayout, "00:00")
+	return start.Sub(midnight), end.Sub(midnight), nil
+}
+
+func (w AvailabilityWindow) contains(now time.Time) bool {
+	local := now.In(cityLocation)
+	if len(w.Weekdays) > 0 && !slices.Contains(w.Weekdays, local.Weekday()) {
+		return false
+	}
+	start, end, err := w.bounds()
+	if err != nil {
+		return false
+	}
+	sinceMidnight := local.Sub(time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, cityLocation))
+	return sinceMidnight >= start && sinceMidnight < end
+}
+
+// ValidateSchedule checks the availability dates and recurring windows.
+func (b *Benefit) ValidateSchedule() error {
+	if !b.AvailableFrom.IsZero() && !b.AvailableUntil.IsZero() && !b.AvailableUntil.After(b.AvailableFrom) {
+		return fmt.Errorf("%w: availableUntil must be after availableFrom", ErrInvalidAvailabilityWindow)
+	}
+	for _, window := range b.AvailabilityWindows {
+		if _, _, err := window.bounds(); err != nil {
+			return err
+		}
+		for _, day := range window.Weekdays {
+			if day < time.Sunday || day > time.Saturday {
+				return fmt.Errorf("%w: weekday %d", ErrInvalidAvailabilityWindow, day)
+			}
+		}
+	}
+	return nil
+}
+
+// isScheduled reports whether now falls into the benefit's campaign dates
+// and, if it has any, one of its recurring windows.
+func (b *Benefit) isScheduled(now time.Time) bool {
+	if !b.AvailableFrom.IsZero() && now.Before(b.AvailableFrom) {
+		return false
+	}
+	if !b.AvailableUntil.IsZero() && !now.Before(b.AvailableUntil) {
+		return false
+	}
+	if len(b.AvailabilityWindows) == 0 {
+		return true
+	}
+	for _, window := range b.AvailabilityWindows {
+		if window.contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// isComingSoon reports whether the benefit cannot be bought now but will
+// become available later.
+func (b *Benefit) isComingSoon(now time.Time) bool {
+	if b.Expired || b.isPastExpiration(now) {
+		return false
+	}
+	if !b.AvailableUntil.IsZero() && !now.Before(b.AvailableUntil) {
+		return false
+	}
+	return !b.isScheduled(now)
+}
+
+// filterAvailable keeps the benefits that can be bought now and, if
+// includeComingSoon is set, those that will become available later.
+func filterAvailable(benefits []Benefit, now time.Time, includeComingSoon bool) []Benefit {
+	filtered := []Benefit{}
+	for _, benefit := range benefits {
+		if benefit.IsAvailable(now) {
+			filtered = append(filtered, benefit)
+			continue
+		}
+		if includeComingSoon && benefit.isComingSoon(now) {
+			benefit.ComingSoon = true
+			filtered = append(filtered, benefit)
+		}
+	}
+	return filtered
+}
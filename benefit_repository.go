@@ -8,41 +8,119 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 var ErrBenefitNotFound = errors.New("benefit not found")
+var ErrBenefitUnavailable = errors.New("benefit is no longer available")
+var ErrOutOfStock = errors.New("benefit is out of stock")
+var ErrOwnedBenefitNotFound = errors.New("owned benefit not found")
+var ErrVoucherNotRedeemable = errors.New("voucher is expired or already redeemed")
+var ErrInvalidVoucherCode = errors.New("invalid voucher code")
 
 type Benefit struct {
-	Id             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	Name           string             `json:"name" bson:"name"`
-	Category       string             `json:"category" bson:"category"` // Changed to string
-	Description    string             `json:"description" bson:"description"`
-	ImageUrl       string             `json:"imageUrl" bson:"imageUrl"`
-	Price          int                `json:"price" bson:"price"`
-	InStock        int                `json:"inStock" bson:"inStock"`
-	ExpirationDate time.Time          `json:"expirationDate" bson:"expirationDate"`
+	Id                   primitive.ObjectID            `json:"id" bson:"_id,omitempty"`
+	ExternalId           string                        `json:"externalId,omitempty" bson:"externalId,omitempty"`
+	Name                 string                        `json:"name" bson:"name"`
+	Category             string                        `json:"category" bson:"category"`
+	Description          string                        `json:"description" bson:"description"`
+	ImageUrl             string                        `json:"imageUrl" bson:"imageUrl"`
+	Thumbnails           map[string]string             `json:"thumbnails,omitempty" bson:"thumbnails,omitempty"`
+	Price                int                           `json:"price" bson:"price"`
+	InStock              int                           `json:"inStock" bson:"inStock"`
+	ExpirationDate       time.Time                     `json:"expirationDate" bson:"expirationDate"`
+	Expired              bool                          `json:"expired" bson:"expired"`
+	Limits               *PurchaseLimits               `json:"limits,omitempty" bson:"limits"`
+	AvailableFrom        time.Time                     `json:"availableFrom" bson:"availableFrom"`
+	AvailableUntil       time.Time                     `json:"availableUntil" bson:"availableUntil"`
+	AvailabilityWindows  []AvailabilityWindow          `json:"availabilityWindows,omitempty" bson:"availabilityWindows"`
+	ComingSoon           bool                          `json:"comingSoon,omitempty" bson:"-"`
+	PartnerId            *primitive.ObjectID           `json:"partnerId,omitempty" bson:"partnerId,omitempty"`
+	Translations         map[string]BenefitTranslation `json:"translations,omitempty" bson:"translations"`
+	Locale               string                        `json:"locale,omitempty" bson:"-"`
+	LocalizedName        string                        `json:"localizedName,omitempty" bson:"-"`
+	LocalizedDescription string                        `json:"localizedDescription,omitempty" bson:"-"`
+}
+
+// IsAvailable reports whether the benefit can be bought at the given time.
+func (b *Benefit) IsAvailable(now time.Time) bool {
+	if b.Expired || b.isPastExpiration(now) {
+		return false
+	}
+	return b.isScheduled(now)
+}
+
+func (b *Benefit) isPastExpiration(now time.Time) bool {
+	return !b.ExpirationDate.IsZero() && !b.ExpirationDate.After(now)
 }
 
 type OwnedBenefit struct {
-	Id             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	OwnerId        primitive.ObjectID `json:"ownerId" bson:"ownerId"`
-	BenefitId      primitive.ObjectID `json:"benefitId" bson:"benefitId"`
-	Purchased      time.Time          `json:"purchased" bson:"purchased"`
-	Content        string             `json:"content" bson:"content"`
-	ExpirationDate time.Time          `json:"expirationDate" bson:"expirationDate"`
+	Id               primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	OwnerId          primitive.ObjectID  `json:"ownerId" bson:"ownerId"`
+	BenefitId        primitive.ObjectID  `json:"benefitId" bson:"benefitId"`
+	Purchased        time.Time           `json:"purchased" bson:"purchased"`
+	Content          string              `json:"content" bson:"content"`
+	VoucherCode      string              `json:"voucherCode,omitempty" bson:"voucherCode,omitempty"`
+	ExpirationDate   time.Time           `json:"expirationDate" bson:"expirationDate"`
+	Expired          bool                `json:"expired" bson:"expired"`
+	PricePaid        int                 `json:"pricePaid" bson:"pricePaid"`
+	PromoCode        string              `json:"promoCode,omitempty" bson:"promoCode,omitempty"`
+	BundleId         *primitive.ObjectID `json:"bundleId,omitempty" bson:"bundleId,omitempty"`
+	BundlePurchaseId *primitive.ObjectID `json:"bundlePurchaseId,omitempty" bson:"bundlePurchaseId,omitempty"`
+	CheckoutId       *primitive.ObjectID `json:"checkoutId,omitempty" bson:"checkoutId,omitempty"`
+	RedeemedAt       *time.Time          `json:"redeemedAt,omitempty" bson:"redeemedAt,omitempty"`
+	PartnerId        *primitive.ObjectID `json:"partnerId,omitempty" bson:"partnerId,omitempty"`
+}
+
+// expiredByFilter matches documents with an expiration date at or before now
+// that have not been marked as expired yet.
+func expiredByFilter(now time.Time) bson.M {
+	return bson.M{
+		"expired":        bson.M{"$ne": true},
+		"expirationDate": bson.M{"$gt": time.Time{}, "$lte": now},
+	}
 }
 
 type BenefitRepository struct {
 	client                     *mongo.Client
 	benefitCollection          *mongo.Collection
 	purchasedBenefitCollection *mongo.Collection
+	purchaseCounterCollection  *mongo.Collection
 }
 
-func NewBenefitRepository(client *mongo.Client, benefitCollection *mongo.Collection, purchasedBenefitCollection *mongo.Collection) (*BenefitRepository, error) {
+func NewBenefitRepository(client *mongo.Client, benefitCollection *mongo.Collection, purchasedBenefitCollection *mongo.Collection, purchaseCounterCollection *mongo.Collection) (*BenefitRepository, error) {
+	// External IDs come from bulk imports and identify a benefit across them
+	_, err := benefitCollection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "externalId", Value: 1}},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"externalId": bson.M{"$type": "string"}}),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Voucher codes identify the owned benefit when a partner redeems it
+	_, err = purchasedBenefitCollection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "voucherCode", Value: 1}},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"voucherCode": bson.M{"$type": "string"}}),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Counters of purchase windows and days are dropped once they are over
+	_, err = purchaseCounterCollection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	return &BenefitRepository{
 		client:                     client,
 		benefitCollection:          benefitCollection,
 		purchasedBenefitCollection: purchasedBenefitCollection,
+		purchaseCounterCollection:  purchaseCounterCollection,
 	}, nil
 }
 
@@ -68,7 +146,7 @@ func (r *BenefitRepository) GetAllBenefits(ctx context.Context) ([]Benefit, erro
 
 func (r *BenefitRepository) GetBenefitByID(ctx context.Context, id primitive.ObjectID) (*Benefit, error) {
 	var benefit Benefit
-	err := r.benefitCollection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&benefit)
+	err := r.benefitCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&benefit)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, ErrBenefitNotFound
@@ -79,7 +157,15 @@ func (r *BenefitRepository) GetBenefitByID(ctx context.Context, id primitive.Obj
 	return &benefit, nil
 }
 
+// AddBenefit stores a new benefit. Benefits created without an external ID
+// get their own ID as one so they can be exported and imported again.
 func (r *BenefitRepository) AddBenefit(ctx context.Context, benefit *Benefit) (*Benefit, error) {
+	if benefit.ExternalId == "" {
+		if benefit.Id.IsZero() {
+			benefit.Id = primitive.NewObjectID()
+		}
+		benefit.ExternalId = benefit.Id.Hex()
+	}
 	result, err := r.benefitCollection.InsertOne(ctx, benefit)
 	if err != nil {
 		return nil, err
@@ -112,6 +198,65 @@ func (r *BenefitRepository) DeleteBenefit(ctx context.Context, id primitive.Obje
 	return nil
 }
 
+// GetBenefitsByExternalIds returns the benefits the external IDs belong to,
+// keyed by external ID.
+func (r *BenefitRepository) GetBenefitsByExternalIds(ctx context.Context, externalIds []string) (map[string]Benefit, error) {
+	cursor, err := r.benefitCollection.Find(ctx, bson.M{"externalId": bson.M{"$in": externalIds}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	benefits := map[string]Benefit{}
+	for cursor.Next(ctx) {
+		var benefit Benefit
+		if err := cursor.Decode(&benefit); err != nil {
+			return nil, err
+		}
+		benefits[benefit.ExternalId] = benefit
+	}
+	return benefits, cursor.Err()
+}
+
+// importedBenefitFields are the benefit fields a catalogue import sets.
+// Fields the service maintains itself, such as the expired flag and the
+// thumbnails, are kept.
+var importedBenefitFields = []string{"externalId", "name", "category", "description", "imageUrl", "price", "inStock", "expirationDate", "availableFrom", "availableUntil", "partnerId", "limits", "availabilityWindows", "translations"}
+
+// UpsertBenefitsByExternalId creates or replaces the imported fields of
+// benefits matched by external ID in one bulk write. An empty image URL
+// keeps the uploaded image.
+func (r *BenefitRepository) UpsertBenefitsByExternalId(ctx context.Context, benefits []Benefit) error {
+	models := make([]mongo.WriteModel, 0, len(benefits))
+	for _, benefit := range benefits {
+		data, err := bson.Marshal(benefit)
+		if err != nil {
+			return err
+		}
+		var document bson.M
+		if err := bson.Unmarshal(data, &document); err != nil {
+			return err
+		}
+		fields := bson.M{}
+		for _, field := range importedBenefitFields {
+			if value, found := document[field]; found {
+				fields[field] = value
+			}
+		}
+		if benefit.ImageUrl == "" {
+			delete(fields, "imageUrl")
+		}
+
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"externalId": benefit.ExternalId}).
+			SetUpdate(bson.M{"$set": fields, "$setOnInsert": bson.M{"expired": false}}).
+			SetUpsert(true))
+	}
+
+	_, err := r.benefitCollection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
 func (r *BenefitRepository) GetFilteredBenefits(ctx context.Context, filter bson.M) ([]Benefit, error) {
 	cursor, err := r.benefitCollection.Find(ctx, filter)
 	if err != nil {
@@ -152,6 +297,97 @@ func (r *BenefitRepository) GetOwnedBenefits(ctx context.Context, userID primiti
 	return ownedBenefits, nil
 }
 
+// GetOwnedBenefitByVoucherCode finds the owned benefit with the given
+// voucher code. Codes that generateVoucherCode cannot have produced fail
+// with ErrInvalidVoucherCode without a lookup.
+func (r *BenefitRepository) GetOwnedBenefitByVoucherCode(ctx context.Context, code string) (*OwnedBenefit, error) {
+	if !voucherCodePattern.MatchString(code) {
+		return nil, ErrInvalidVoucherCode
+	}
+	var ownedBenefit OwnedBenefit
+	err := r.purchasedBenefitCollection.FindOne(ctx, bson.M{"voucherCode": code}).Decode(&ownedBenefit)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrOwnedBenefitNotFound
+		}
+		return nil, err
+	}
+
+	return &ownedBenefit, nil
+}
+
+// RedeemOwnedBenefit marks the owned benefit as used. It fails with
+// ErrVoucherNotRedeemable if it expired or was redeemed before.
+func (r *BenefitRepository) RedeemOwnedBenefit(ctx context.Context, ownedBenefit *OwnedBenefit, now time.Time) error {
+	filter := bson.M{
+		"_id":        ownedBenefit.Id,
+		"expired":    bson.M{"$ne": true},
+		"redeemedAt": bson.M{"$exists": false},
+	}
+	update := bson.M{"$set": bson.M{"redeemedAt": now}}
+	result, err := r.purchasedBenefitCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrVoucherNotRedeemable
+	}
+	ownedBenefit.RedeemedAt = &now
+	return nil
+}
+
+// BackfillExternalIds gives benefits created without an external ID their
+// own ID as one.
+func (r *BenefitRepository) BackfillExternalIds(ctx context.Context) error {
+	filter := bson.M{"externalId": bson.M{"$not": bson.M{"$type": "string"}}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"externalId": bson.M{"$toString": "$_id"}}}},
+	}
+	_, err := r.benefitCollection.UpdateMany(ctx, filter, update)
+	return err
+}
+
+// BackfillVoucherCodes copies the voucher code out of the content of owned
+// benefits bought before the code had a field of its own. Placeholder
+// content is left without a code.
+func (r *BenefitRepository) BackfillVoucherCodes(ctx context.Context) error {
+	filter := bson.M{
+		"voucherCode": bson.M{"$exists": false},
+		"content":     bson.M{"$regex": voucherCodePattern.String()},
+	}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"voucherCode": "$content"}}},
+	}
+	_, err := r.purchasedBenefitCollection.UpdateMany(ctx, filter, update)
+	return err
+}
+
+// BackfillOwnedBenefitPartners copies the partner of each benefit onto
+// owned benefits bought before owned benefits recorded their partner, so
+// settlements include them.
+func (r *BenefitRepository) BackfillOwnedBenefitPartners(ctx context.Context) error {
+	benefits, err := r.GetFilteredBenefits(ctx, bson.M{"partnerId": bson.M{"$type": "objectId"}})
+	if err != nil {
+		return err
+	}
+	for _, benefit := range benefits {
+		filter := bson.M{"benefitId": benefit.Id, "partnerId": bson.M{"$exists": false}}
+		update := bson.M{"$set": bson.M{"partnerId": benefit.PartnerId}}
+		if _, err := r.purchasedBenefitCollection.UpdateMany(ctx, filter, update); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *BenefitRepository) CountOwnedBenefits(ctx context.Context, filter bson.M) (int, error) {
+	count, err := r.purchasedBenefitCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
 func (r *BenefitRepository) AddPurchasedBenefit(ctx context.Context, ownedBenefit *OwnedBenefit) (*OwnedBenefit, error) {
 	result, err := r.purchasedBenefitCollection.InsertOne(ctx, ownedBenefit)
 	if err != nil {
@@ -164,3 +400,165 @@ func (r *BenefitRepository) AddPurchasedBenefit(ctx context.Context, ownedBenefi
 	ownedBenefit.Id = generatedID
 	return ownedBenefit, nil
 }
+
+// CountActiveBenefitsByCategory counts benefits that can be bought at the
+// given time per category slug. Expiration and campaign dates are matched
+// by the database; only the recurring windows of benefits that have any
+// are checked here.
+func (r *BenefitRepository) CountActiveBenefitsByCategory(ctx context.Context, now time.Time) (map[string]int, error) {
+	hasWindows := bson.M{"$gt": bson.A{bson.M{"$size": bson.M{"$ifNull": bson.A{"$availabilityWindows", bson.A{}}}}, 0}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"expired":        bson.M{"$ne": true},
+			"expirationDate": bson.M{"$not": bson.M{"$gt": time.Time{}, "$lte": now}},
+			"availableFrom":  bson.M{"$not": bson.M{"$gt": now}},
+			"availableUntil": bson.M{"$not": bson.M{"$gt": time.Time{}, "$lte": now}},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":       "$category",
+			"count":     bson.M{"$sum": bson.M{"$cond": bson.A{hasWindows, 0, 1}}},
+			"scheduled": bson.M{"$push": bson.M{"$cond": bson.A{hasWindows, "$availabilityWindows", "$$REMOVE"}}},
+		}}},
+	}
+	cursor, err := r.benefitCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	counts := map[string]int{}
+	for cursor.Next(ctx) {
+		var group struct {
+			Category  string                 `bson:"_id"`
+			Count     int                    `bson:"count"`
+			Scheduled [][]AvailabilityWindow `bson:"scheduled"`
+		}
+		if err := cursor.Decode(&group); err != nil {
+			return nil, err
+		}
+		for _, windows := range group.Scheduled {
+			benefit := Benefit{AvailabilityWindows: windows}
+			if benefit.isScheduled(now) {
+				group.Count++
+			}
+		}
+		if group.Count > 0 {
+			counts[group.Category] = group.Count
+		}
+	}
+	return counts, cursor.Err()
+}
+
+// GetDistinctCategories lists the category values used by benefits.
+func (r *BenefitRepository) GetDistinctCategories(ctx context.Context) ([]string, error) {
+	values, err := r.benefitCollection.Distinct(ctx, "category", bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	categories := make([]string, 0, len(values))
+	for _, value := range values {
+		if category, ok := value.(string); ok {
+			categories = append(categories, category)
+		}
+	}
+	return categories, nil
+}
+
+func (r *BenefitRepository) CountBenefitsInCategory(ctx context.Context, slug string) (int, error) {
+	count, err := r.benefitCollection.CountDocuments(ctx, bson.M{"category": slug})
+	if err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+// RenameCategory moves all benefits of oldSlug to newSlug.
+func (r *BenefitRepository) RenameCategory(ctx context.Context, oldSlug string, newSlug string) error {
+	_, err := r.benefitCollection.UpdateMany(ctx, bson.M{"category": oldSlug}, bson.M{"$set": bson.M{"category": newSlug}})
+	return err
+}
+
+// DecrementStock takes quantity units of the benefit out of stock, failing
+// if fewer units are left.
+func (r *BenefitRepository) DecrementStock(ctx context.Context, id primitive.ObjectID, quantity int) error {
+	filter := bson.M{"_id": id, "inStock": bson.M{"$gte": quantity}}
+	update := bson.M{"$inc": bson.M{"inStock": -quantity}}
+	result, err := r.benefitCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrOutOfStock
+	}
+	return nil
+}
+
+// IncrementStock puts quantity units of the benefit back into stock.
+func (r *BenefitRepository) IncrementStock(ctx context.Context, id primitive.ObjectID, quantity int) error {
+	_, err := r.benefitCollection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"inStock": quantity}})
+	return err
+}
+
+func (r *BenefitRepository) GetBenefitsExpiredBy(ctx context.Context, now time.Time) ([]Benefit, error) {
+	return r.GetFilteredBenefits(ctx, expiredByFilter(now))
+}
+
+// MarkBenefitExpired flags the benefit as expired. It returns false if the
+// benefit had already been flagged.
+func (r *BenefitRepository) MarkBenefitExpired(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	filter := bson.M{"_id": id, "expired": bson.M{"$ne": true}}
+	update := bson.M{"$set": bson.M{"expired": true}}
+	result, err := r.benefitCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// SetBenefitImage points the benefit at an uploaded image and its thumbnails.
+func (r *BenefitRepository) SetBenefitImage(ctx context.Context, id primitive.ObjectID, imageUrl string, thumbnails map[string]string) (*Benefit, error) {
+	filter := bson.M{"_id": id}
+	update := bson.M{"$set": bson.M{"imageUrl": imageUrl, "thumbnails": thumbnails}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var benefit Benefit
+	err := r.benefitCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&benefit)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrBenefitNotFound
+		}
+		return nil, err
+	}
+	return &benefit, nil
+}
+
+func (r *BenefitRepository) GetOwnedBenefitsExpiredBy(ctx context.Context, now time.Time) ([]OwnedBenefit, error) {
+	cursor, err := r.purchasedBenefitCollection.Find(ctx, expiredByFilter(now))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var ownedBenefits []OwnedBenefit
+	for cursor.Next(ctx) {
+		var ownedBenefit OwnedBenefit
+		err := cursor.Decode(&ownedBenefit)
+		if err != nil {
+			return nil, err
+		}
+		ownedBenefits = append(ownedBenefits, ownedBenefit)
+	}
+
+	return ownedBenefits, nil
+}
+
+// MarkOwnedBenefitExpired flags the owned benefit as expired. It returns false
+// if the owned benefit had already been flagged.
+func (r *BenefitRepository) MarkOwnedBenefitExpired(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	filter := bson.M{"_id": id, "expired": bson.M{"$ne": true}}
+	update := bson.M{"$set": bson.M{"expired": true}}
+	result, err := r.purchasedBenefitCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// @Summary Get bundles
+// @Description Retrieves all benefit bundles
+// @Tags bundles
+// @Accept json
+// @Produce json
+// @Success 200 {array} Bundle "List of bundles"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /bundles [get]
+func getBundles(c *gin.Context, repo *BundleRepository) {
+	ctx := c.Request.Context()
+	bundles, err := repo.GetAllBundles(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, bundles)
+}
+
+// @Summary Get a single bundle
+// @Description Retrieves a single bundle by its ID
+// @Tags bundles
+// @Accept json
+// @Produce json
+// @Param id path string true "Bundle ID"
+// @Success 200 {object} Bundle "Single bundle"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 404 {object} ErrorResponse "Bundle not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /bundles/{id} [get]
+func getBundle(c *gin.Context, repo *BundleRepository) {
+	ctx := c.Request.Context()
+	bundleId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	bundle, err := repo.GetBundleByID(ctx, bundleId)
+	if err != nil {
+		if err == ErrBundleNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, bundle)
+}
+
+// @Summary Add a new bundle
+// @Description Adds a bundle of existing benefits sold for a single price
+// @Tags bundles
+// @Accept json
+// @Produce json
+// @Param bundle body Bundle true "Bundle to add"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 201 {object} Bundle "Bundle created"
+// @Failure 400 {object} ErrorResponse "Invalid bundle format or unknown benefit"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /bundles [post]
+func addBundle(c *gin.Context, repo *BundleRepository, benefitRepo *BenefitRepository) {
+	ctx := c.Request.Context()
+	var bundle Bundle
+	if err := c.ShouldBindJSON(&bundle); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if status, err := validateBundle(c, &bundle, benefitRepo); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	savedBundle, err := repo.AddBundle(ctx, &bundle)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAuditChange(c, "bundles", savedBundle.Id, nil, savedBundle)
+	c.JSON(http.StatusCreated, savedBundle)
+}
+
+// @Summary Update a bundle
+// @Description Updates a bundle by its ID
+// @Tags bundles
+// @Accept json
+// @Produce json
+// @Param id path string true "Bundle ID"
+// @Param bundle body Bundle true "Updated bundle information"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 200 {object} Bundle "Bundle updated successfully"
+// @Failure 400 {object} ErrorResponse "Invalid ID or bundle format"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 404 {object} ErrorResponse "Bundle not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /bundles/{id} [put]
+func updateBundle(c *gin.Context, repo *BundleRepository, benefitRepo *BenefitRepository) {
+	ctx := c.Request.Context()
+	bundleId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	existingBundle, err := repo.GetBundleByID(ctx, bundleId)
+	if err != nil {
+		if err == ErrBundleNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var updatedBundle Bundle
+	if err := c.ShouldBindJSON(&updatedBundle); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if updatedBundle.Id != bundleId {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bundle ID in URL does not match ID in request body"})
+		return
+	}
+	if status, err := validateBundle(c, &updatedBundle, benefitRepo); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	savedBundle, err := repo.UpdateBundle(ctx, &updatedBundle)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAuditChange(c, "bundles", bundleId, existingBundle, savedBundle)
+
+	c.JSON(http.StatusOK, savedBundle)
+}
+
+// @Summary Delete a bundle
+// @Description Deletes a bundle by its ID
+// @Tags bundles
+// @Accept json
+// @Produce json
+// @Param id path string true "Bundle ID"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 200 {object} object "Bundle deleted successfully"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /bundles/{id} [delete]
+func deleteBundle(c *gin.Context, repo *BundleRepository) {
+	ctx := c.Request.Context()
+	bundleId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deletedBundle, err := repo.GetBundleByID(ctx, bundleId)
+	if err != nil && err != ErrBundleNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := repo.DeleteBundle(ctx, bundleId); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if deletedBundle != nil {
+		recordAuditChange(c, "bundles", bundleId, deletedBundle, nil)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Bundle deleted successfully"})
+}
+
+// validateBundle checks the bundle fields and that every component benefit
+// exists. It returns the HTTP status to respond with on failure.
+func validateBundle(c *gin.Context, bundle *Bundle, benefitRepo *BenefitRepository) (int, error) {
+	if err := bundle.Validate(); err != nil {
+		return http.StatusBadRequest, err
+	}
+	for _, benefitId := range bundle.BenefitIds {
+		if _, err := benefitRepo.GetBenefitByID(c.Request.Context(), benefitId); err != nil {
+			if err == ErrBenefitNotFound {
+				return http.StatusBadRequest, fmt.Errorf("benefit %s not found", benefitId.Hex())
+			}
+			return http.StatusInternalServerError, err
+		}
+	}
+	return http.StatusOK, nil
+}
+
+// @Summary Buy a bundle
+// @Description Buys all benefits of a bundle in one transaction, debiting the wallet once. Fails if any component is unavailable.
+// @Tags bundles
+// @Accept json
+// @Produce json
+// @Param bundle_id path string true "Bundle ID"
+// @Param user_id body string true "User ID"
+// @Success 200 {array} OwnedBenefit "Purchased benefits"
+// @Failure 400 {object} ErrorResponse "Invalid ID format, insufficient funds, component unavailable, out of stock or purchase limit reached"
+// @Failure 404 {object} ErrorResponse "Bundle, benefit or wallet not found"
+// @Failure 429 {object} ErrorResponse "Too many requests, see Retry-After"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /bundles/{bundle_id}/buy [post]
+func buyBundle(c *gin.Context, bundleRepo *BundleRepository, benefitRepo *BenefitRepository, walletRepo *WalletRepository, outbox *OutboxRepository, live *LiveUpdates) {
+	// Start a session
+	session, err := benefitRepo.client.StartSession()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start session"})
+		return
+	}
+	defer session.EndSession(c.Request.Context())
+
+	bundleId, err := primitive.ObjectIDFromHex(c.Param("bundle_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req struct {
+		UserID primitive.ObjectID `json:"user_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	transaction := func(sessCtx mongo.SessionContext) (any, error) {
+		now := time.Now()
+
+		// Step 1: Get the bundle and check every component
+		bundle, err := bundleRepo.GetBundleByID(sessCtx, bundleId)
+		if err != nil {
+			return nil, err
+		}
+		benefits := make([]*Benefit, 0, len(bundle.BenefitIds))
+		for _, benefitId := range bundle.BenefitIds {
+			benefit, err := benefitRepo.GetBenefitByID(sessCtx, benefitId)
+			if err != nil {
+				return nil, err
+			}
+			if !benefit.IsAvailable(now) {
+				return nil, fmt.Errorf("%s: %w", benefit.Name, ErrBenefitUnavailable)
+			}
+			if err := benefitRepo.ClaimPurchases(sessCtx, benefit, req.UserID, 1, now); err != nil {
+				return nil, fmt.Errorf("%s: %w", benefit.Name, err)
+			}
+			benefits = append(benefits, benefit)
+		}
+
+		// Step 2: Take every component out of stock
+		for _, benefit := range benefits {
+			if err := benefitRepo.DecrementStock(sessCtx, benefit.Id, 1); err != nil {
+				return nil, fmt.Errorf("%s: %w", benefit.Name, err)
+			}
+		}
+
+		// Step 3: Debit the wallet once for the whole bundle
+		wallet, err := walletRepo.GetWalletByUserID(sessCtx, req.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if err := wallet.DebitTokens(bundle.Price); err != nil {
+			return nil, err
+		}
+		if _, err := walletRepo.UpdateWallet(sessCtx, wallet); err != nil {
+			return nil, err
+		}
+
+		// Step 4: Add one owned benefit per component
+		bundlePurchaseId := primitive.NewObjectID()
+		prices := splitBundlePrice(bundle.Price, benefits)
+		ownedBenefits := make([]OwnedBenefit, 0, len(benefits))
+		for i, benefit := range benefits {
+			voucherCode, err := generateVoucherCode()
+			if err != nil {
+				return nil, err
+			}
+			ownedBenefit := OwnedBenefit{
+				OwnerId:          req.UserID,
+				BenefitId:        benefit.Id,
+				Purchased:        now,
+				Content:          voucherCode,
+				VoucherCode:      voucherCode,
+				ExpirationDate:   benefit.ExpirationDate,
+				PricePaid:        prices[i],
+				PartnerId:        benefit.PartnerId,
+				BundleId:         &bundle.Id,
+				BundlePurchaseId: &bundlePurchaseId,
+			}
+			savedOwnedBenefit, err := benefitRepo.AddPurchasedBenefit(sessCtx, &ownedBenefit)
+			if err != nil {
+				return nil, err
+			}
+			ownedBenefits = append(ownedBenefits, *savedOwnedBenefit)
+		}
+
+		// Step 5: Let other services know about the purchases
+		if err := outbox.Add(sessCtx, benefitPurchasedEvents(ownedBenefits)...); err != nil {
+			return nil, err
+		}
+
+		return ownedBenefits, nil
+	}
+
+	result, err := session.WithTransaction(c.Request.Context(), transaction)
+	if err != nil {
+		c.JSON(bundleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ownedBenefits := result.([]OwnedBenefit)
+	live.WalletChanged(c.Request.Context(), req.UserID)
+	for _, ownedBenefit := range ownedBenefits {
+		live.StockChanged(c.Request.Context(), ownedBenefit.BenefitId)
+	}
+	c.JSON(http.StatusOK, ownedBenefits)
+}
+
+func bundleErrorStatus(err error) int {
+	if errors.Is(err, ErrBundleNotFound) {
+		return http.StatusNotFound
+	}
+	return purchaseErrorStatus(err)
+}
+
+// splitBundlePrice spreads the bundle price over its components in
+// proportion to their list prices so that each owned benefit records what
+// was paid for it. Rounding leftovers go to the last component.
+func splitBundlePrice(price int, benefits []*Benefit) []int {
+	prices := make([]int, len(benefits))
+	if len(benefits) == 0 {
+		return prices
+	}
+
+	listTotal := 0
+	for _, benefit := range benefits {
+		listTotal += benefit.Price
+	}
+
+	allocated := 0
+	for i, benefit := range benefits[:len(benefits)-1] {
+		if listTotal > 0 {
+			prices[i] = price * benefit.Price / listTotal
+		} else {
+			prices[i] = price / len(benefits)
+		}
+		allocated += prices[i]
+	}
+	prices[len(benefits)-1] = price - allocated
+
+	return prices
+}
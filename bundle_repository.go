@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var ErrBundleNotFound = errors.New("bundle not found")
+
+// Bundle is a set of benefits sold together for a single price.
+type Bundle struct {
+	Id          primitive.ObjectID   `json:"id" bson:"_id,omitempty"`
+	Name        string               `json:"name" bson:"name"`
+	Description string               `json:"description" bson:"description"`
+	ImageUrl    string               `json:"imageUrl" bson:"imageUrl"`
+	BenefitIds  []primitive.ObjectID `json:"benefitIds" bson:"benefitIds" swaggertype:"array,string"`
+	Price       int                  `json:"price" bson:"price"`
+}
+
+func (b *Bundle) Validate() error {
+	if b.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(b.BenefitIds) == 0 {
+		return errors.New("a bundle needs at least one benefit")
+	}
+	seen := make(map[primitive.ObjectID]bool, len(b.BenefitIds))
+	for _, benefitId := range b.BenefitIds {
+		if seen[benefitId] {
+			return fmt.Errorf("benefit %s is in the bundle more than once", benefitId.Hex())
+		}
+		seen[benefitId] = true
+	}
+	if b.Price < 0 {
+		return errors.New("price must not be negative")
+	}
+	return nil
+}
+
+type BundleRepository struct {
+	collection *mongo.Collection
+}
+
+func NewBundleRepository(collection *mongo.Collection) (*BundleRepository, error) {
+	return &BundleRepository{
+		collection: collection,
+	}, nil
+}
+
+func (r *BundleRepository) GetAllBundles(ctx context.Context) ([]Bundle, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var bundles []Bundle
+	for cursor.Next(ctx) {
+		var bundle Bundle
+		err := cursor.Decode(&bundle)
+		if err != nil {
+			return nil, err
+		}
+		bundles = append(bundles, bundle)
+	}
+
+	return bundles, nil
+}
+
+func (r *BundleRepository) GetBundleByID(ctx context.Context, id primitive.ObjectID) (*Bundle, error) {
+	var bundle Bundle
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&bundle)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrBundleNotFound
+		}
+		return nil, err
+	}
+
+	return &bundle, nil
+}
+
+func (r *BundleRepository) AddBundle(ctx context.Context, bundle *Bundle) (*Bundle, error) {
+	result, err := r.collection.InsertOne(ctx, bundle)
+	if err != nil {
+		return nil, err
+	}
+	generatedID, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return nil, errors.New("failed to convert InsertedID to ObjectID")
+	}
+	bundle.Id = generatedID
+	return bundle, nil
+}
+
+func (r *BundleRepository) UpdateBundle(ctx context.Context, bundle *Bundle) (*Bundle, error) {
+	filter := bson.M{"_id": bundle.Id}
+	update := bson.M{"$set": bundle}
+	_, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return nil, err
+	}
+
+	return bundle, nil
+}
+
+func (r *BundleRepository) DeleteBundle(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ReceiptItem struct {
+	BenefitId primitive.ObjectID `json:"benefitId"`
+	Name      string             `json:"name"`
+	UnitPrice int                `json:"unitPrice"`
+	Quantity  int                `json:"quantity"`
+	Total     int                `json:"total"`
+}
+
+// Receipt summarizes a checkout. Its ID is stored as checkoutId on every
+// owned benefit the checkout created.
+type Receipt struct {
+	Id            primitive.ObjectID `json:"id"`
+	UserId        primitive.ObjectID `json:"userId"`
+	Items         []ReceiptItem      `json:"items"`
+	Total         int                `json:"total"`
+	PurchasedAt   time.Time          `json:"purchasedAt"`
+	OwnedBenefits []OwnedBenefit     `json:"ownedBenefits"`
+}
+
+// @Summary Get a cart
+// @Description Retrieves the user's cart with snapshot prices
+// @Tags carts
+// @Accept json
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Success 200 {object} Cart "User's cart"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /carts/{user_id} [get]
+func getCart(c *gin.Context, repo *CartRepository) {
+	ctx := c.Request.Context()
+	userId, err := primitive.ObjectIDFromHex(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cart, err := repo.GetCartByUserID(ctx, userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, cart.WithTotal())
+}
+
+// @Summary Add an item to a cart
+// @Description Adds units of a benefit to the user's cart, snapshotting its current price
+// @Tags carts
+// @Accept json
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param benefit_id body string true "Benefit ID"
+// @Param quantity body int false "Units to add, defaults to 1. A cart holds at most 100 units of a benefit."
+// @Success 200 {object} Cart "Updated cart"
+// @Failure 400 {object} ErrorResponse "Invalid request format or quantity, or benefit unavailable"
+// @Failure 404 {object} ErrorResponse "Benefit not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /carts/{user_id}/items [post]
+func addCartItem(c *gin.Context, repo *CartRepository, benefitRepo *BenefitRepository) {
+	ctx := c.Request.Context()
+	userId, err := primitive.ObjectIDFromHex(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req struct {
+		BenefitID primitive.ObjectID `json:"benefit_id"`
+		Quantity  int                `json:"quantity"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+	if req.Quantity < 0 || req.Quantity > maxPurchaseQuantity {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("quantity must be between 1 and %d", maxPurchaseQuantity)})
+		return
+	}
+
+	benefit, err := benefitRepo.GetBenefitByID(ctx, req.BenefitID)
+	if err != nil {
+		c.JSON(purchaseErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if !benefit.IsAvailable(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrBenefitUnavailable.Error()})
+		return
+	}
+
+	cart, err := repo.GetCartByUserID(ctx, userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	quantity := cart.quantityOf(benefit.Id) + req.Quantity
+	if quantity > maxPurchaseQuantity {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s: at most %d units of a benefit can be bought at once", ErrCartQuantityTooLarge, maxPurchaseQuantity)})
+		return
+	}
+	cart.SetItem(benefit, quantity, time.Now())
+
+	savedCart, err := repo.SaveCart(ctx, cart)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, savedCart.WithTotal())
+}
+
+// @Summary Change an item quantity
+// @Description Sets how many units of a benefit are in the user's cart. A quantity of 0 removes the item.
+// @Tags carts
+// @Accept json
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param benefit_id path string true "Benefit ID"
+// @Param quantity body int true "New quantity, at most 100"
+// @Success 200 {object} Cart "Updated cart"
+// @Failure 400 {object} ErrorResponse "Invalid request format or quantity"
+// @Failure 404 {object} ErrorResponse "Item not in cart"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /carts/{user_id}/items/{benefit_id} [put]
+func updateCartItem(c *gin.Context, repo *CartRepository, benefitRepo *BenefitRepository) {
+	ctx := c.Request.Context()
+	userId, err := primitive.ObjectIDFromHex(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	benefitId, err := primitive.ObjectIDFromHex(c.Param("benefit_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req struct {
+		Quantity int `json:"quantity"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Quantity < 0 || req.Quantity > maxPurchaseQuantity {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("quantity must be between 0 and %d", maxPurchaseQuantity)})
+		return
+	}
+
+	cart, err := repo.GetCartByUserID(ctx, userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if cart.quantityOf(benefitId) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrCartItemNotFound.Error()})
+		return
+	}
+
+	benefit, err := benefitRepo.GetBenefitByID(ctx, benefitId)
+	if err != nil {
+		c.JSON(purchaseErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	cart.SetItem(benefit, req.Quantity, time.Now())
+
+	savedCart, err := repo.SaveCart(ctx, cart)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, savedCart.WithTotal())
+}
+
+// @Summary Remove an item from a cart
+// @Description Removes a benefit from the user's cart
+// @Tags carts
+// @Accept json
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param benefit_id path string true "Benefit ID"
+// @Success 200 {object} Cart "Updated cart"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 404 {object} ErrorResponse "Item not in cart"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /carts/{user_id}/items/{benefit_id} [delete]
+func removeCartItem(c *gin.Context, repo *CartRepository) {
+	ctx := c.Request.Context()
+	userId, err := primitive.ObjectIDFromHex(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	benefitId, err := primitive.ObjectIDFromHex(c.Param("benefit_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cart, err := repo.GetCartByUserID(ctx, userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := cart.RemoveItem(benefitId); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	savedCart, err := repo.SaveCart(ctx, cart)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, savedCart.WithTotal())
+}
+
+// @Summary Check out a cart
+// @Description Buys every item in the user's cart in one transaction. Either all items are bought or none. If prices changed since the items were added, the cart is refreshed and the checkout fails so the user can review it.
+// @Tags carts
+// @Accept json
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Success 200 {object} Receipt "Combined receipt"
+// @Failure 400 {object} ErrorResponse "Empty cart, too many units of a benefit, insufficient funds, item unavailable, out of stock or purchase limit reached"
+// @Failure 404 {object} ErrorResponse "Benefit or wallet not found"
+// @Failure 409 {object} ErrorResponse "Prices changed"
+// @Failure 429 {object} ErrorResponse "Too many requests, see Retry-After"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /carts/{user_id}/checkout [post]
+func checkoutCart(c *gin.Context, cartRepo *CartRepository, benefitRepo *BenefitRepository, walletRepo *WalletRepository, outbox *OutboxRepository, live *LiveUpdates) {
+	// Start a session
+	session, err := benefitRepo.client.StartSession()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start session"})
+		return
+	}
+	defer session.EndSession(c.Request.Context())
+
+	userId, err := primitive.ObjectIDFromHex(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	transaction := func(sessCtx mongo.SessionContext) (any, error) {
+		now := time.Now()
+
+		// Step 1: Load the cart and check every item against the catalogue
+		cart, err := cartRepo.GetCartByUserID(sessCtx, userId)
+		if err != nil {
+			return nil, err
+		}
+		if len(cart.Items) == 0 {
+			return nil, ErrCartEmpty
+		}
+		benefits := make([]*Benefit, 0, len(cart.Items))
+		for _, item := range cart.Items {
+			benefit, err := benefitRepo.GetBenefitByID(sessCtx, item.BenefitId)
+			if err != nil {
+				return nil, err
+			}
+			if benefit.Price != item.UnitPrice {
+				return nil, ErrCartPriceChanged
+			}
+			if !benefit.IsAvailable(now) {
+				return nil, fmt.Errorf("%s: %w", benefit.Name, ErrBenefitUnavailable)
+			}
+			// Carts saved before the cap could hold more
+			if item.Quantity > maxPurchaseQuantity {
+				return nil, fmt.Errorf("%s: %w", benefit.Name, ErrCartQuantityTooLarge)
+			}
+			if err := benefitRepo.ClaimPurchases(sessCtx, benefit, userId, item.Quantity, now); err != nil {
+				return nil, fmt.Errorf("%s: %w", benefit.Name, err)
+			}
+			if err := benefitRepo.DecrementStock(sessCtx, benefit.Id, item.Quantity); err != nil {
+				return nil, fmt.Errorf("%s: %w", benefit.Name, err)
+			}
+			benefits = append(benefits, benefit)
+		}
+
+		// Step 2: Debit the wallet once for the whole cart
+		cart.WithTotal()
+		wallet, err := walletRepo.GetWalletByUserID(sessCtx, userId)
+		if err != nil {
+			return nil, err
+		}
+		if err := wallet.DebitTokens(cart.Total); err != nil {
+			return nil, err
+		}
+		if _, err := walletRepo.UpdateWallet(sessCtx, wallet); err != nil {
+			return nil, err
+		}
+
+		// Step 3: Add one owned benefit per purchased unit
+		receipt := Receipt{
+			Id:          primitive.NewObjectID(),
+			UserId:      userId,
+			Total:       cart.Total,
+			PurchasedAt: now,
+		}
+		for i, item := range cart.Items {
+			for range item.Quantity {
+				voucherCode, err := generateVoucherCode()
+				if err != nil {
+					return nil, err
+				}
+				ownedBenefit := OwnedBenefit{
+					OwnerId:        userId,
+					BenefitId:      item.BenefitId,
+					Purchased:      now,
+					Content:        voucherCode,
+					VoucherCode:    voucherCode,
+					ExpirationDate: benefits[i].ExpirationDate,
+					PricePaid:      item.UnitPrice,
+					PartnerId:      benefits[i].PartnerId,
+					CheckoutId:     &receipt.Id,
+				}
+				savedOwnedBenefit, err := benefitRepo.AddPurchasedBenefit(sessCtx, &ownedBenefit)
+				if err != nil {
+					return nil, err
+				}
+				receipt.OwnedBenefits = append(receipt.OwnedBenefits, *savedOwnedBenefit)
+			}
+			receipt.Items = append(receipt.Items, ReceiptItem{
+				BenefitId: item.BenefitId,
+				Name:      item.Name,
+				UnitPrice: item.UnitPrice,
+				Quantity:  item.Quantity,
+				Total:     item.UnitPrice * item.Quantity,
+			})
+		}
+
+		// Step 4: Let other services know about the purchases
+		if err := outbox.Add(sessCtx, benefitPurchasedEvents(receipt.OwnedBenefits)...); err != nil {
+			return nil, err
+		}
+
+		// Step 5: Empty the cart
+		if err := cartRepo.ClearCart(sessCtx, userId); err != nil {
+			return nil, err
+		}
+
+		return receipt, nil
+	}
+
+	result, err := session.WithTransaction(c.Request.Context(), transaction)
+	if err != nil {
+		if errors.Is(err, ErrCartPriceChanged) {
+			refreshCartPrices(c, cartRepo, benefitRepo, userId)
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(checkoutErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	receipt := result.(Receipt)
+	live.WalletChanged(c.Request.Context(), userId)
+	for _, item := range receipt.Items {
+		live.StockChanged(c.Request.Context(), item.BenefitId)
+	}
+	c.JSON(http.StatusOK, receipt)
+}
+
+// refreshCartPrices updates the snapshot prices in the user's cart to the
+// current catalogue prices.
+func refreshCartPrices(c *gin.Context, cartRepo *CartRepository, benefitRepo *BenefitRepository, userId primitive.ObjectID) {
+	ctx := c.Request.Context()
+	cart, err := cartRepo.GetCartByUserID(ctx, userId)
+	if err != nil {
+		return
+	}
+	for _, item := range cart.Items {
+		benefit, err := benefitRepo.GetBenefitByID(ctx, item.BenefitId)
+		if err != nil {
+			continue
+		}
+		cart.SetItem(benefit, item.Quantity, item.AddedAt)
+	}
+	cartRepo.SaveCart(ctx, cart)
+}
+
+func checkoutErrorStatus(err error) int {
+	if errors.Is(err, ErrCartEmpty) || errors.Is(err, ErrCartQuantityTooLarge) {
+		return http.StatusBadRequest
+	}
+	return purchaseErrorStatus(err)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrCartEmpty = errors.New("cart is empty")
+var ErrCartItemNotFound = errors.New("cart item not found")
+var ErrCartPriceChanged = errors.New("prices in the cart have changed, please review the cart")
+var ErrCartQuantityTooLarge = errors.New("too many units of one benefit in the cart")
+
+// CartItem keeps the benefit's name and price from the moment it was added
+// so the cart can be shown without loading the catalogue.
+type CartItem struct {
+	BenefitId primitive.ObjectID `json:"benefitId" bson:"benefitId"`
+	Name      string             `json:"name" bson:"name"`
+	UnitPrice int                `json:"unitPrice" bson:"unitPrice"`
+	Quantity  int                `json:"quantity" bson:"quantity"`
+	AddedAt   time.Time          `json:"addedAt" bson:"addedAt"`
+}
+
+type Cart struct {
+	Id        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserId    primitive.ObjectID `json:"userId" bson:"userId"`
+	Items     []CartItem         `json:"items" bson:"items"`
+	Total     int                `json:"total" bson:"-"`
+	UpdatedAt time.Time          `json:"updatedAt" bson:"updatedAt"`
+}
+
+// SetItem adds the benefit to the cart or changes its quantity. A quantity
+// of zero removes the item.
+func (c *Cart) SetItem(benefit *Benefit, quantity int, now time.Time) {
+	for i := range c.Items {
+		if c.Items[i].BenefitId != benefit.Id {
+			continue
+		}
+		if quantity == 0 {
+			c.Items = append(c.Items[:i], c.Items[i+1:]...)
+			return
+		}
+		c.Items[i].Name = benefit.Name
+		c.Items[i].UnitPrice = benefit.Price
+		c.Items[i].Quantity = quantity
+		return
+	}
+	if quantity == 0 {
+		return
+	}
+	c.Items = append(c.Items, CartItem{
+		BenefitId: benefit.Id,
+		Name:      benefit.Name,
+		UnitPrice: benefit.Price,
+		Quantity:  quantity,
+		AddedAt:   now,
+	})
+}
+
+func (c *Cart) quantityOf(benefitId primitive.ObjectID) int {
+	for _, item := range c.Items {
+		if item.BenefitId == benefitId {
+			return item.Quantity
+		}
+	}
+	return 0
+}
+
+func (c *Cart) RemoveItem(benefitId primitive.ObjectID) error {
+	for i := range c.Items {
+		if c.Items[i].BenefitId == benefitId {
+			c.Items = append(c.Items[:i], c.Items[i+1:]...)
+			return nil
+		}
+	}
+	return ErrCartItemNotFound
+}
+
+// WithTotal fills Total from the snapshot prices of the items.
+func (c *Cart) WithTotal() *Cart {
+	c.Total = 0
+	for _, item := range c.Items {
+		c.Total += item.UnitPrice * item.Quantity
+	}
+	return c
+}
+
+type CartRepository struct {
+	collection *mongo.Collection
+}
+
+func NewCartRepository(collection *mongo.Collection) (*CartRepository, error) {
+	return &CartRepository{
+		collection: collection,
+	}, nil
+}
+
+// GetCartByUserID returns the user's cart, or an empty cart if the user has
+// never added anything.
+func (r *CartRepository) GetCartByUserID(ctx context.Context, userId primitive.ObjectID) (*Cart, error) {
+	var cart Cart
+	err := r.collection.FindOne(ctx, bson.M{"userId": userId}).Decode(&cart)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return &Cart{UserId: userId, Items: []CartItem{}}, nil
+		}
+		return nil, err
+	}
+
+	return &cart, nil
+}
+
+func (r *CartRepository) SaveCart(ctx context.Context, cart *Cart) (*Cart, error) {
+	cart.UpdatedAt = time.Now()
+	filter := bson.M{"userId": cart.UserId}
+	update := bson.M{"$set": bson.M{
+		"userId":    cart.UserId,
+		"items":     cart.Items,
+		"updatedAt": cart.UpdatedAt,
+	}}
+	_, err := r.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return nil, err
+	}
+
+	return cart, nil
+}
+
+func (r *CartRepository) ClearCart(ctx context.Context, userId primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"userId": userId})
+	return err
+}
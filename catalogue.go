@@ -0,0 +1,305 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	importActionCreate = "create"
+	importActionUpdate = "update"
+)
+
+// catalogueColumns are the CSV columns of the benefit catalogue. Every
+// locale other than the default one adds name_<locale> and
+// description_<locale> columns. Availability windows are written as a JSON
+// array, as in the JSON format.
+var catalogueColumns = []string{"externalId", "name", "category", "description", "imageUrl", "price", "inStock", "expirationDate", "availableFrom", "availableUntil", "partnerId", "maxPerUser", "maxPerUserPerWindow", "windowHours", "maxPerDay", "availabilityWindows"}
+
+// ImportRowResult tells what happened, or would happen, to one imported row.
+type ImportRowResult struct {
+	Row        int    `json:"row"`
+	ExternalId string `json:"externalId"`
+	Action     string `json:"action,omitempty" enums:"create,update"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ImportReport summarizes a bulk import. Nothing is written when the
+// import is a dry run or when any row failed.
+type ImportReport struct {
+	DryRun  bool              `json:"dryRun"`
+	Applied bool              `json:"applied"`
+	Total   int               `json:"total"`
+	Created int               `json:"created"`
+	Updated int               `json:"updated"`
+	Failed  int               `json:"failed"`
+	Rows    []ImportRowResult `json:"rows"`
+}
+
+// importRow is a parsed row waiting for validation. Rows that could not
+// be parsed carry the parse error.
+type importRow struct {
+	number  int
+	benefit Benefit
+	err     error
+}
+
+func translatedColumns() []string {
+	var columns []string
+	for _, locale := range supportedLocales {
+		if locale != defaultLocale {
+			columns = append(columns, "name_"+locale, "description_"+locale)
+		}
+	}
+	return columns
+}
+
+// parseCatalogueCSV reads benefits from a CSV file whose first line names
+// the columns. Unknown and repeated columns are rejected, missing ones are
+// left empty.
+func parseCatalogueCSV(r io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, errors.New("CSV file is empty")
+		}
+		return nil, err
+	}
+
+	known := map[string]bool{}
+	for _, column := range append(catalogueColumns, translatedColumns()...) {
+		known[column] = true
+	}
+	seen := map[string]bool{}
+	for _, column := range header {
+		if !known[column] {
+			return nil, fmt.Errorf("unknown column %q", column)
+		}
+		if seen[column] {
+			return nil, fmt.Errorf("column %q appears more than once", column)
+		}
+		seen[column] = true
+	}
+
+	var rows []importRow
+	for number := 1; ; number++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		values := map[string]string{}
+		for i, column := range header {
+			values[column] = strings.TrimSpace(record[i])
+		}
+		benefit, err := benefitFromCSV(values)
+		rows = append(rows, importRow{number: number, benefit: benefit, err: err})
+	}
+	return rows, nil
+}
+
+func benefitFromCSV(values map[string]string) (Benefit, error) {
+	benefit := Benefit{
+		ExternalId:  values["externalId"],
+		Name:        values["name"],
+		Category:    values["category"],
+		Description: values["description"],
+		ImageUrl:    values["imageUrl"],
+	}
+
+	var err error
+	if benefit.Price, err = parseOptionalInt(values["price"]); err != nil {
+		return benefit, fmt.Errorf("invalid price: %w", err)
+	}
+	if benefit.InStock, err = parseOptionalInt(values["inStock"]); err != nil {
+		return benefit, fmt.Errorf("invalid inStock: %w", err)
+	}
+	if benefit.ExpirationDate, err = parseOptionalTime(values["expirationDate"]); err != nil {
+		return benefit, fmt.Errorf("invalid expirationDate: %w", err)
+	}
+	if benefit.AvailableFrom, err = parseOptionalTime(values["availableFrom"]); err != nil {
+		return benefit, fmt.Errorf("invalid availableFrom: %w", err)
+	}
+	if benefit.AvailableUntil, err = parseOptionalTime(values["availableUntil"]); err != nil {
+		return benefit, fmt.Errorf("invalid availableUntil: %w", err)
+	}
+	if partnerId := values["partnerId"]; partnerId != "" {
+		id, err := primitive.ObjectIDFromHex(partnerId)
+		if err != nil {
+			return benefit, fmt.Errorf("invalid partnerId: %w", err)
+		}
+		benefit.PartnerId = &id
+	}
+
+	var limits PurchaseLimits
+	limitColumns := []struct {
+		name  string
+		value *int
+	}{
+		{"maxPerUser", &limits.MaxPerUser},
+		{"maxPerUserPerWindow", &limits.MaxPerUserPerWindow},
+		{"windowHours", &limits.WindowHours},
+		{"maxPerDay", &limits.MaxPerDay},
+	}
+	for _, column := range limitColumns {
+		if *column.value, err = parseOptionalInt(values[column.name]); err != nil {
+			return benefit, fmt.Errorf("invalid %s: %w", column.name, err)
+		}
+	}
+	if limits != (PurchaseLimits{}) {
+		benefit.Limits = &limits
+	}
+	if windows := values["availabilityWindows"]; windows != "" {
+		if err := json.Unmarshal([]byte(windows), &benefit.AvailabilityWindows); err != nil {
+			return benefit, fmt.Errorf("invalid availabilityWindows: %w", err)
+		}
+	}
+
+	for _, locale := range supportedLocales {
+		name := values["name_"+locale]
+		description := values["description_"+locale]
+		if locale == defaultLocale || (name == "" && description == "") {
+			continue
+		}
+		if benefit.Translations == nil {
+			benefit.Translations = map[string]BenefitTranslation{}
+		}
+		benefit.Translations[locale] = BenefitTranslation{Name: name, Description: description}
+	}
+	return benefit, nil
+}
+
+func parseOptionalInt(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func formatOptionalInt(value int) string {
+	if value == 0 {
+		return ""
+	}
+	return strconv.Itoa(value)
+}
+
+func parseOptionalTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func formatOptionalTime(value time.Time) string {
+	if value.IsZero() {
+		return ""
+	}
+	return value.Format(time.RFC3339)
+}
+
+// parseCatalogueJSON reads benefits from a JSON array.
+func parseCatalogueJSON(r io.Reader) ([]importRow, error) {
+	var benefits []Benefit
+	if err := json.NewDecoder(r).Decode(&benefits); err != nil {
+		return nil, err
+	}
+	rows := make([]importRow, 0, len(benefits))
+	for i, benefit := range benefits {
+		rows = append(rows, importRow{number: i + 1, benefit: benefit})
+	}
+	return rows, nil
+}
+
+// writeCatalogueCSV writes benefits in the format parseCatalogueCSV reads.
+func writeCatalogueCSV(w io.Writer, benefits []Benefit) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(append(catalogueColumns, translatedColumns()...)); err != nil {
+		return err
+	}
+	for _, benefit := range benefits {
+		partnerId := ""
+		if benefit.PartnerId != nil {
+			partnerId = benefit.PartnerId.Hex()
+		}
+		var limits PurchaseLimits
+		if benefit.Limits != nil {
+			limits = *benefit.Limits
+		}
+		windows := ""
+		if len(benefit.AvailabilityWindows) > 0 {
+			data, err := json.Marshal(benefit.AvailabilityWindows)
+			if err != nil {
+				return err
+			}
+			windows = string(data)
+		}
+		record := []string{
+			benefit.ExternalId,
+			benefit.Name,
+			benefit.Category,
+			benefit.Description,
+			benefit.ImageUrl,
+			strconv.Itoa(benefit.Price),
+			strconv.Itoa(benefit.InStock),
+			formatOptionalTime(benefit.ExpirationDate),
+			formatOptionalTime(benefit.AvailableFrom),
+			formatOptionalTime(benefit.AvailableUntil),
+			partnerId,
+			formatOptionalInt(limits.MaxPerUser),
+			formatOptionalInt(limits.MaxPerUserPerWindow),
+			formatOptionalInt(limits.WindowHours),
+			formatOptionalInt(limits.MaxPerDay),
+			windows,
+		}
+		for _, locale := range supportedLocales {
+			if locale != defaultLocale {
+				translation := benefit.Translations[locale]
+				record = append(record, translation.Name, translation.Description)
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// validateImportedBenefit checks a single imported benefit against the
+// rules of POST /benefits plus the import specific ones.
+func validateImportedBenefit(benefit *Benefit, categories map[string]bool) error {
+	if benefit.ExternalId == "" {
+		return errors.New("externalId is required")
+	}
+	if benefit.Name == "" {
+		return errors.New("name is required")
+	}
+	if benefit.Price < 0 {
+		return errors.New("price must not be negative")
+	}
+	if benefit.InStock < 0 {
+		return errors.New("inStock must not be negative")
+	}
+	if !categories[benefit.Category] {
+		return fmt.Errorf("unknown category %q", benefit.Category)
+	}
+	if err := benefit.ValidateSchedule(); err != nil {
+		return err
+	}
+	if err := benefit.ValidateLimits(); err != nil {
+		return err
+	}
+	return benefit.ValidateTranslations()
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxCatalogueBytes = 10 << 20
+
+// @Summary Import benefits
+// @Description Creates or updates benefits in bulk from a CSV file or a JSON array, matching existing benefits by externalId. Only the catalogue fields are written, so expired benefits stay expired and uploaded thumbnails are kept. The import is all-or-nothing: if any row is invalid nothing is written and the report lists the errors. With dry_run only the report is returned.
+// @Tags benefits
+// @Accept json,text/csv
+// @Produce json
+// @Param format query string false "json or csv, taken from the Content-Type if omitted"
+// @Param dry_run query bool false "Validate without writing anything"
+// @Param benefits body []Benefit true "Benefits as a JSON array or CSV file with a header line"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 200 {object} ImportReport "Import report"
+// @Failure 400 {object} ErrorResponse "Unreadable file or invalid format"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 413 {object} ErrorResponse "File too large"
+// @Failure 422 {object} ImportReport "Some rows are invalid, nothing was imported"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /benefits/import [post]
+func importBenefits(c *gin.Context, repo *BenefitRepository, categoryRepo *CategoryRepository) {
+	ctx := c.Request.Context()
+	format := c.Query("format")
+	if format == "" {
+		format = "json"
+		if strings.HasPrefix(c.ContentType(), "text/csv") {
+			format = "csv"
+		}
+	}
+	dryRun := c.Query("dry_run") == "true"
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxCatalogueBytes)
+	var rows []importRow
+	var err error
+	switch format {
+	case "json":
+		rows, err = parseCatalogueJSON(body)
+	case "csv":
+		rows, err = parseCatalogueCSV(body)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format"})
+		return
+	}
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file must be at most 10 MB"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(rows) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no benefits to import"})
+		return
+	}
+
+	categoryList, err := categoryRepo.GetAllCategories(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	categories := map[string]bool{}
+	for _, category := range categoryList {
+		categories[category.Slug] = true
+	}
+
+	externalIds := make([]string, 0, len(rows))
+	for _, row := range rows {
+		externalIds = append(externalIds, row.benefit.ExternalId)
+	}
+	existing, err := repo.GetBenefitsByExternalIds(ctx, externalIds)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	report := ImportReport{DryRun: dryRun, Total: len(rows), Rows: []ImportRowResult{}}
+	seen := map[string]int{}
+	benefits := make([]Benefit, 0, len(rows))
+	for _, row := range rows {
+		result := ImportRowResult{Row: row.number, ExternalId: row.benefit.ExternalId}
+		err := row.err
+		if err == nil {
+			err = validateImportedBenefit(&row.benefit, categories)
+		}
+		if err == nil {
+			if first, ok := seen[row.benefit.ExternalId]; ok {
+				err = errors.New("externalId already used in row " + strconv.Itoa(first))
+			}
+		}
+
+		_, exists := existing[row.benefit.ExternalId]
+		switch {
+		case err != nil:
+			result.Error = err.Error()
+			report.Failed++
+		case exists:
+			result.Action = importActionUpdate
+			report.Updated++
+		default:
+			result.Action = importActionCreate
+			report.Created++
+		}
+		if err == nil {
+			seen[row.benefit.ExternalId] = row.number
+			benefits = append(benefits, row.benefit)
+		}
+		report.Rows = append(report.Rows, result)
+	}
+
+	if report.Failed > 0 {
+		c.JSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+	if dryRun {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	if err := repo.UpsertBenefitsByExternalId(ctx, benefits); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	imported, err := repo.GetBenefitsByExternalIds(ctx, externalIds)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, benefit := range benefits {
+		after := imported[benefit.ExternalId]
+		if before, found := existing[benefit.ExternalId]; found {
+			recordAuditChange(c, "benefits", after.Id, &before, &after)
+		} else {
+			recordAuditChange(c, "benefits", after.Id, nil, &after)
+		}
+	}
+	report.Applied = true
+	c.JSON(http.StatusOK, report)
+}
+
+// @Summary Export benefits
+// @Description Exports the whole benefit catalogue in the format accepted by the import
+// @Tags benefits
+// @Accept json
+// @Produce json,text/csv
+// @Param format query string false "json (default) or csv"
+// @Success 200 {array} Benefit "Benefit catalogue"
+// @Failure 400 {object} ErrorResponse "Invalid format"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /benefits/export [get]
+func exportBenefits(c *gin.Context, repo *BenefitRepository) {
+	ctx := c.Request.Context()
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format"})
+		return
+	}
+
+	benefits, err := repo.GetAllBenefits(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if benefits == nil {
+		benefits = []Benefit{}
+	}
+
+	if format == "json" {
+		c.JSON(http.StatusOK, benefits)
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="benefits.csv"`)
+	c.Header("Content-Type", "text/csv")
+	c.Status(http.StatusOK)
+	if err := writeCatalogueCSV(c.Writer, benefits); err != nil {
+		c.Error(err)
+	}
+}
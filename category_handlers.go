@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// validateBenefitCategory makes sure the benefit refers to an existing category.
+func validateBenefitCategory(ctx context.Context, repo *CategoryRepository, benefit *Benefit) error {
+	if _, err := repo.GetCategoryBySlug(ctx, benefit.Category); err != nil {
+		if err == ErrCategoryNotFound {
+			return fmt.Errorf("unknown category %q", benefit.Category)
+		}
+		return err
+	}
+	return nil
+}
+
+// validateCategoryParent makes sure the parent exists and that setting it
+// does not create a cycle.
+func validateCategoryParent(ctx context.Context, repo *CategoryRepository, category *Category) error {
+	for parentSlug := category.ParentSlug; parentSlug != ""; {
+		parent, err := repo.GetCategoryBySlug(ctx, parentSlug)
+		if err != nil {
+			if err == ErrCategoryNotFound {
+				return fmt.Errorf("unknown parent category %q", parentSlug)
+			}
+			return err
+		}
+		if parent.Id == category.Id || parent.Slug == category.Slug {
+			return errors.New("category parents must not form a cycle")
+		}
+		parentSlug = parent.ParentSlug
+	}
+	return nil
+}
+
+// @Summary Get categories
+// @Description Retrieves all categories with the number of active benefits in each
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Success 200 {array} CategoryWithCount "List of categories"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /categories [get]
+func getCategories(c *gin.Context, repo *CategoryRepository, benefitRepo *BenefitRepository) {
+	ctx := c.Request.Context()
+	categories, err := repo.GetAllCategories(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	counts, err := benefitRepo.CountActiveBenefitsByCategory(ctx, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := make([]CategoryWithCount, 0, len(categories))
+	for _, category := range categories {
+		result = append(result, CategoryWithCount{Category: category, ActiveBenefits: counts[category.Slug]})
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// @Summary Get a single category
+// @Description Retrieves a single category by its ID
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param id path string true "Category ID"
+// @Success 200 {object} Category "Single category"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 404 {object} ErrorResponse "Category not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /categories/{id} [get]
+func getCategory(c *gin.Context, repo *CategoryRepository) {
+	ctx := c.Request.Context()
+	categoryId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := repo.GetCategoryByID(ctx, categoryId)
+	if err != nil {
+		if err == ErrCategoryNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// @Summary Add a new category
+// @Description Adds a category to the taxonomy
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param category body Category true "Category to add"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 201 {object} Category "Category created"
+// @Failure 400 {object} ErrorResponse "Invalid category format or unknown parent"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 409 {object} ErrorResponse "Slug already taken"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /categories [post]
+func addCategory(c *gin.Context, repo *CategoryRepository) {
+	ctx := c.Request.Context()
+	var category Category
+	if err := c.ShouldBindJSON(&category); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := category.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateCategoryParent(ctx, repo, &category); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	savedCategory, err := repo.AddCategory(ctx, &category)
+	if err != nil {
+		if err == ErrCategoryExists {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAuditChange(c, "categories", savedCategory.Id, nil, savedCategory)
+	c.JSON(http.StatusCreated, savedCategory)
+}
+
+// @Summary Update a category
+// @Description Updates a category by its ID. Changing the slug moves its benefits and subcategories along.
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param id path string true "Category ID"
+// @Param category body Category true "Updated category information"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 200 {object} Category "Category updated successfully"
+// @Failure 400 {object} ErrorResponse "Invalid ID or category format"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 404 {object} ErrorResponse "Category not found"
+// @Failure 409 {object} ErrorResponse "Slug already taken"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /categories/{id} [put]
+func updateCategory(c *gin.Context, repo *CategoryRepository, benefitRepo *BenefitRepository) {
+	ctx := c.Request.Context()
+	categoryId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	existing, err := repo.GetCategoryByID(ctx, categoryId)
+	if err != nil {
+		if err == ErrCategoryNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var updatedCategory Category
+	if err := c.ShouldBindJSON(&updatedCategory); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if updatedCategory.Id != categoryId {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Category ID in URL does not match ID in request body"})
+		return
+	}
+	if err := updatedCategory.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateCategoryParent(ctx, repo, &updatedCategory); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	savedCategory, err := repo.UpdateCategory(ctx, &updatedCategory)
+	if err != nil {
+		if err == ErrCategoryExists {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if existing.Slug != savedCategory.Slug {
+		if err := benefitRepo.RenameCategory(ctx, existing.Slug, savedCategory.Slug); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := repo.RenameParent(ctx, existing.Slug, savedCategory.Slug); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	recordAuditChange(c, "categories", categoryId, existing, savedCategory)
+
+	c.JSON(http.StatusOK, savedCategory)
+}
+
+// @Summary Delete a category
+// @Description Deletes a category that has no benefits and no subcategories
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param id path string true "Category ID"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 200 {object} object "Category deleted successfully"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 404 {object} ErrorResponse "Category not found"
+// @Failure 409 {object} ErrorResponse "Category still in use"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /categories/{id} [delete]
+func deleteCategory(c *gin.Context, repo *CategoryRepository, benefitRepo *BenefitRepository) {
+	ctx := c.Request.Context()
+	categoryId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := repo.GetCategoryByID(ctx, categoryId)
+	if err != nil {
+		if err == ErrCategoryNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	benefits, err := benefitRepo.CountBenefitsInCategory(ctx, category.Slug)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	children, err := repo.CountChildren(ctx, category.Slug)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if benefits > 0 || children > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": ErrCategoryInUse.Error()})
+		return
+	}
+
+	if err := repo.DeleteCategory(ctx, categoryId); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAuditChange(c, "categories", categoryId, category, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrCategoryNotFound = errors.New("category not found")
+var ErrCategoryExists = errors.New("category with this slug already exists")
+var ErrCategoryInUse = errors.New("category still has benefits or subcategories")
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Polish letters are spelled without diacritics in slugs
+var slugTransliterator = strings.NewReplacer("ą", "a", "ć", "c", "ę", "e", "ł", "l", "ń", "n", "ó", "o", "ś", "s", "ź", "z", "ż", "z")
+
+// slugify turns free text such as "Kultura & Sztuka" into a slug such as
+// "kultura-sztuka". It returns an empty string if the text has no letters
+// or digits that can be kept.
+func slugify(text string) string {
+	var slug strings.Builder
+	separate := false
+	for _, r := range slugTransliterator.Replace(strings.ToLower(text)) {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			separate = true
+			continue
+		}
+		if separate && slug.Len() > 0 {
+			slug.WriteByte('-')
+		}
+		separate = false
+		slug.WriteRune(r)
+	}
+	return slug.String()
+}
+
+// Category is an entry of the managed benefit taxonomy. Benefits refer to
+// categories by slug.
+type Category struct {
+	Id         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Slug       string             `json:"slug" bson:"slug" example:"public-transport"`
+	Name       string             `json:"name" bson:"name"`
+	ParentSlug string             `json:"parentSlug,omitempty" bson:"parentSlug,omitempty"`
+	Icon       string             `json:"icon,omitempty" bson:"icon,omitempty"`
+}
+
+// CategoryWithCount is a category with the number of its active benefits.
+type CategoryWithCount struct {
+	Category       `bson:",inline"`
+	ActiveBenefits int `json:"activeBenefits"`
+}
+
+func (c *Category) Validate() error {
+	if !slugPattern.MatchString(c.Slug) {
+		return errors.New("slug must consist of lowercase letters, digits and single dashes")
+	}
+	if c.Name == "" {
+		return errors.New("name is required")
+	}
+	if c.ParentSlug == c.Slug {
+		return errors.New("a category cannot be its own parent")
+	}
+	return nil
+}
+
+type CategoryRepository struct {
+	collection *mongo.Collection
+}
+
+func NewCategoryRepository(collection *mongo.Collection) (*CategoryRepository, error) {
+	_, err := collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "slug", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &CategoryRepository{
+		collection: collection,
+	}, nil
+}
+
+func (r *CategoryRepository) GetAllCategories(ctx context.Context) ([]Category, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "slug", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var categories []Category
+	for cursor.Next(ctx) {
+		var category Category
+		err := cursor.Decode(&category)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+
+	return categories, nil
+}
+
+func (r *CategoryRepository) GetCategoryByID(ctx context.Context, id primitive.ObjectID) (*Category, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *CategoryRepository) GetCategoryBySlug(ctx context.Context, slug string) (*Category, error) {
+	return r.findOne(ctx, bson.M{"slug": slug})
+}
+
+func (r *CategoryRepository) findOne(ctx context.Context, filter bson.M) (*Category, error) {
+	var category Category
+	err := r.collection.FindOne(ctx, filter).Decode(&category)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrCategoryNotFound
+		}
+		return nil, err
+	}
+
+	return &category, nil
+}
+
+// GetDescendantSlugs returns the slug and the slugs of all its subcategories.
+func (r *CategoryRepository) GetDescendantSlugs(ctx context.Context, slug string) ([]string, error) {
+	categories, err := r.GetAllCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slugs := []string{slug}
+	for i := 0; i < len(slugs); i++ {
+		for _, category := range categories {
+			if category.ParentSlug == slugs[i] {
+				slugs = append(slugs, category.Slug)
+			}
+		}
+	}
+	return slugs, nil
+}
+
+func (r *CategoryRepository) CountChildren(ctx context.Context, slug string) (int, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"parentSlug": slug})
+	if err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+func (r *CategoryRepository) AddCategory(ctx context.Context, category *Category) (*Category, error) {
+	result, err := r.collection.InsertOne(ctx, category)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrCategoryExists
+		}
+		return nil, err
+	}
+	generatedID, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return nil, errors.New("failed to convert InsertedID to ObjectID")
+	}
+	category.Id = generatedID
+	return category, nil
+}
+
+func (r *CategoryRepository) UpdateCategory(ctx context.Context, category *Category) (*Category, error) {
+	_, err := r.collection.ReplaceOne(ctx, bson.M{"_id": category.Id}, category)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrCategoryExists
+		}
+		return nil, err
+	}
+
+	return category, nil
+}
+
+// RenameParent points subcategories of oldSlug to newSlug.
+func (r *CategoryRepository) RenameParent(ctx context.Context, oldSlug string, newSlug string) error {
+	_, err := r.collection.UpdateMany(ctx, bson.M{"parentSlug": oldSlug}, bson.M{"$set": bson.M{"parentSlug": newSlug}})
+	return err
+}
+
+func (r *CategoryRepository) DeleteCategory(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ChallengeStatus is a user's progress on an active challenge in its
+// current window.
+type ChallengeStatus struct {
+	Challenge Challenge         `json:"challenge"`
+	Progress  ChallengeProgress `json:"progress"`
+}
+
+// validateChallenge checks the challenge and that its reward benefit exists.
+func validateChallenge(c *gin.Context, challenge *Challenge, benefitRepo *BenefitRepository) bool {
+	if err := challenge.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if challenge.RewardBenefitId != nil {
+		if _, err := benefitRepo.GetBenefitByID(c.Request.Context(), *challenge.RewardBenefitId); err != nil {
+			if err == ErrBenefitNotFound {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "reward benefit not found"})
+				return false
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return false
+		}
+	}
+	return true
+}
+
+// @Summary Get challenges
+// @Description Retrieves the challenges running now, or all challenges including disabled and past ones
+// @Tags challenges
+// @Accept json
+// @Produce json
+// @Param all query boolean false "Include challenges that are not running now"
+// @Success 200 {array} Challenge "List of challenges"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /challenges [get]
+func getChallenges(c *gin.Context, repo *ChallengeRepository) {
+	ctx := c.Request.Context()
+	var challenges []Challenge
+	var err error
+	if c.Query("all") == "true" {
+		challenges, err = repo.GetAllChallenges(ctx)
+	} else {
+		challenges, err = repo.GetActiveChallenges(ctx, time.Now())
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, challenges)
+}
+
+// @Summary Get a single challenge
+// @Description Retrieves a challenge by its ID
+// @Tags challenges
+// @Accept json
+// @Produce json
+// @Param id path string true "Challenge ID"
+// @Success 200 {object} Challenge "Single challenge"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 404 {object} ErrorResponse "Challenge not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /challenges/{id} [get]
+func getChallenge(c *gin.Context, repo *ChallengeRepository) {
+	challengeId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	challenge, err := repo.GetChallengeByID(c.Request.Context(), challengeId)
+	if err != nil {
+		if err == ErrChallengeNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, challenge)
+}
+
+// @Summary Add a challenge
+// @Description Adds a challenge counting actions of one type, or consecutive days with them, within the challenge period and rewarding its completion with tokens and/or a free benefit
+// @Tags challenges
+// @Accept json
+// @Produce json
+// @Param challenge body Challenge true "Challenge to add"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 201 {object} Challenge "Challenge created"
+// @Failure 400 {object} ErrorResponse "Invalid challenge"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /challenges [post]
+func addChallenge(c *gin.Context, repo *ChallengeRepository, benefitRepo *BenefitRepository) {
+	var challenge Challenge
+	if err := c.ShouldBindJSON(&challenge); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !validateChallenge(c, &challenge, benefitRepo) {
+		return
+	}
+
+	challenge.Id = primitive.NilObjectID
+	savedChallenge, err := repo.AddChallenge(c.Request.Context(), &challenge)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAuditChange(c, "challenges", savedChallenge.Id, nil, savedChallenge)
+	c.JSON(http.StatusCreated, savedChallenge)
+}
+
+// @Summary Update a challenge
+// @Description Updates a challenge by its ID. Completed progress and rewards already given are not affected.
+// @Tags challenges
+// @Accept json
+// @Produce json
+// @Param id path string true "Challenge ID"
+// @Param challenge body Challenge true "Updated challenge"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 200 {object} Challenge "Challenge updated successfully"
+// @Failure 400 {object} ErrorResponse "Invalid ID or challenge"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 404 {object} ErrorResponse "Challenge not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /challenges/{id} [put]
+func updateChallenge(c *gin.Context, repo *ChallengeRepository, benefitRepo *BenefitRepository) {
+	challengeId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	existingChallenge, err := repo.GetChallengeByID(c.Request.Context(), challengeId)
+	if err != nil {
+		if err == ErrChallengeNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var challenge Challenge
+	if err := c.ShouldBindJSON(&challenge); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if challenge.Id != challengeId {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Challenge ID in URL does not match ID in request body"})
+		return
+	}
+	if !validateChallenge(c, &challenge, benefitRepo) {
+		return
+	}
+
+	savedChallenge, err := repo.UpdateChallenge(c.Request.Context(), &challenge)
+	if err != nil {
+		if err == ErrChallengeNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAuditChange(c, "challenges", challengeId, existingChallenge, savedChallenge)
+	c.JSON(http.StatusOK, savedChallenge)
+}
+
+// @Summary Delete a challenge
+// @Description Deletes a challenge by its ID
+// @Tags challenges
+// @Accept json
+// @Produce json
+// @Param id path string true "Challenge ID"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 200 {object} object "Challenge deleted successfully"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 404 {object} ErrorResponse "Challenge not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /challenges/{id} [delete]
+func deleteChallenge(c *gin.Context, repo *ChallengeRepository) {
+	challengeId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deletedChallenge, err := repo.GetChallengeByID(c.Request.Context(), challengeId)
+	if err != nil {
+		if err == ErrChallengeNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := repo.DeleteChallenge(c.Request.Context(), challengeId); err != nil {
+		if err == ErrChallengeNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAuditChange(c, "challenges", challengeId, deletedChallenge, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Challenge deleted successfully"})
+}
+
+// @Summary Get a user's challenge progress
+// @Description Retrieves the user's progress on every challenge running now, in each challenge's current window
+// @Tags challenges
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {array} ChallengeStatus "Progress per active challenge"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /wallets/{id}/challenges [get]
+func getUserChallenges(c *gin.Context, repo *ChallengeRepository) {
+	ctx := c.Request.Context()
+	userId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	challenges, err := repo.GetActiveChallenges(ctx, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	challengeIds := make([]primitive.ObjectID, 0, len(challenges))
+	for _, challenge := range challenges {
+		challengeIds = append(challengeIds, challenge.Id)
+	}
+	progress, err := repo.GetUserProgress(ctx, userId, challengeIds, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	progressByChallenge := make(map[primitive.ObjectID]ChallengeProgress, len(progress))
+	for _, p := range progress {
+		progressByChallenge[p.ChallengeId] = p
+	}
+
+	statuses := make([]ChallengeStatus, 0, len(challenges))
+	for _, challenge := range challenges {
+		windowStart, windowEnd := challenge.Window(now)
+		p, found := progressByChallenge[challenge.Id]
+		if !found || !p.WindowStart.Equal(windowStart) {
+			p = ChallengeProgress{
+				ChallengeId: challenge.Id,
+				UserId:      userId,
+				WindowStart: windowStart,
+				WindowEnd:   windowEnd,
+			}
+		}
+		p.Target = challenge.Target
+		statuses = append(statuses, ChallengeStatus{Challenge: challenge, Progress: p})
+	}
+	c.JSON(http.StatusOK, statuses)
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrChallengeNotFound = errors.New("challenge not found")
+var ErrInvalidChallenge = errors.New("invalid challenge")
+var ErrChallengeProgressNotFound = errors.New("challenge progress not found")
+
+const (
+	// ChallengeKindCount completes after Target actions in the window.
+	ChallengeKindCount = "count"
+	// ChallengeKindStreak completes after actions on Target consecutive
+	// days in the window.
+	ChallengeKindStreak = "streak"
+)
+
+const (
+	// ChallengePeriodOnce gives each user a single window spanning the
+	// whole challenge.
+	ChallengePeriodOnce   = "once"
+	ChallengePeriodDaily  = "daily"
+	ChallengePeriodWeekly = "weekly"
+)
+
+// Challenge is a goal over reported actions, e.g. using a bike 5 times a
+// week. Users complete it at most once per window and get RewardTokens,
+// a free unit of RewardBenefitId, or both.
+type Challenge struct {
+	Id              primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	Name            string              `json:"name" bson:"name"`
+	Description     string              `json:"description" bson:"description"`
+	ActionType      string              `json:"actionType" bson:"actionType" example:"bike"`
+	Kind            string              `json:"kind" bson:"kind" enums:"count,streak"`
+	Target          int                 `json:"target" bson:"target"`
+	Period          string              `json:"period" bson:"period" enums:"once,daily,weekly"`
+	StartsAt        time.Time           `json:"startsAt" bson:"startsAt"`
+	EndsAt          time.Time           `json:"endsAt" bson:"endsAt"`
+	RewardTokens    int                 `json:"rewardTokens" bson:"rewardTokens"`
+	RewardBenefitId *primitive.ObjectID `json:"rewardBenefitId,omitempty" bson:"rewardBenefitId,omitempty"`
+	Enabled         bool                `json:"enabled" bson:"enabled"`
+}
+
+func (ch *Challenge) Validate() error {
+	if ch.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidChallenge)
+	}
+	if !slugPattern.MatchString(ch.ActionType) {
+		return fmt.Errorf("%w: actionType must be lowercase letters, digits and dashes", ErrInvalidChallenge)
+	}
+	if ch.Kind != ChallengeKindCount && ch.Kind != ChallengeKindStreak {
+		return fmt.Errorf("%w: kind must be count or streak", ErrInvalidChallenge)
+	}
+	if ch.Target <= 0 {
+		return fmt.Errorf("%w: target must be positive", ErrInvalidChallenge)
+	}
+	switch ch.Period {
+	case ChallengePeriodOnce, ChallengePeriodWeekly:
+	case ChallengePeriodDaily:
+		if ch.Kind == ChallengeKindStreak {
+			return fmt.Errorf("%w: a streak cannot run in a daily period", ErrInvalidChallenge)
+		}
+	default:
+		return fmt.Errorf("%w: period must be once, daily or weekly", ErrInvalidChallenge)
+	}
+	if ch.StartsAt.IsZero() {
+		return fmt.Errorf("%w: startsAt is required", ErrInvalidChallenge)
+	}
+	if !ch.EndsAt.IsZero() && !ch.EndsAt.After(ch.StartsAt) {
+		return fmt.Errorf("%w: endsAt must be after startsAt", ErrInvalidChallenge)
+	}
+	if ch.RewardTokens < 0 {
+		return fmt.Errorf("%w: rewardTokens must not be negative", ErrInvalidChallenge)
+	}
+	if ch.RewardTokens == 0 && ch.RewardBenefitId == nil {
+		return fmt.Errorf("%w: a reward in tokens or a benefit is required", ErrInvalidChallenge)
+	}
+	return nil
+}
+
+// IsActiveAt reports whether actions at the given time count towards the
+// challenge.
+func (ch *Challenge) IsActiveAt(at time.Time) bool {
+	if !ch.Enabled || at.Before(ch.StartsAt) {
+		return false
+	}
+	return ch.EndsAt.IsZero() || at.Before(ch.EndsAt)
+}
+
+// Window returns the start and end of the challenge window containing at.
+// The end is zero for an open-ended challenge with a single window.
+func (ch *Challenge) Window(at time.Time) (time.Time, time.Time) {
+	start, end := ch.StartsAt, ch.EndsAt
+	dayStart, weekStart := capWindowStarts(at)
+	switch ch.Period {
+	case ChallengePeriodDaily:
+		start, end = dayStart, dayStart.AddDate(0, 0, 1)
+	case ChallengePeriodWeekly:
+		start, end = weekStart, weekStart.AddDate(0, 0, 7)
+	}
+	if start.Before(ch.StartsAt) {
+		start = ch.StartsAt
+	}
+	if !ch.EndsAt.IsZero() && end.After(ch.EndsAt) {
+		end = ch.EndsAt
+	}
+	return start, end
+}
+
+// ChallengeProgress is a user's progress on a challenge in one window.
+// Days holds the local dates with actions for streak challenges.
+type ChallengeProgress struct {
+	Id                   primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	ChallengeId          primitive.ObjectID  `json:"challengeId" bson:"challengeId"`
+	UserId               primitive.ObjectID  `json:"userId" bson:"userId"`
+	WindowStart          time.Time           `json:"windowStart" bson:"windowStart"`
+	WindowEnd            time.Time           `json:"windowEnd" bson:"windowEnd"`
+	Count                int                 `json:"count" bson:"count"`
+	Target               int                 `json:"target" bson:"target"`
+	Days                 []string            `json:"days,omitempty" bson:"days,omitempty"`
+	Completed            bool                `json:"completed" bson:"completed"`
+	CompletedAt          *time.Time          `json:"completedAt,omitempty" bson:"completedAt,omitempty"`
+	RewardTokens         int                 `json:"rewardTokens" bson:"rewardTokens"`
+	RewardBenefitId      *primitive.ObjectID `json:"rewardBenefitId,omitempty" bson:"rewardBenefitId,omitempty"`
+	RewardOwnedBenefitId *primitive.ObjectID `json:"rewardOwnedBenefitId,omitempty" bson:"rewardOwnedBenefitId,omitempty"`
+	RewardError          string              `json:"rewardError,omitempty" bson:"rewardError,omitempty"`
+	UpdatedAt            time.Time           `json:"updatedAt" bson:"updatedAt"`
+}
+
+type ChallengeRepository struct {
+	challengeCollection *mongo.Collection
+	progressCollection  *mongo.Collection
+}
+
+func NewChallengeRepository(challengeCollection *mongo.Collection, progressCollection *mongo.Collection) (*ChallengeRepository, error) {
+	_, err := challengeCollection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "actionType", Value: 1}, {Key: "enabled", Value: 1}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	_, err = progressCollection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "challengeId", Value: 1}, {Key: "windowStart", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChallengeRepository{
+		challengeCollection: challengeCollection,
+		progressCollection:  progressCollection,
+	}, nil
+}
+
+// activeFilter matches enabled challenges running at the given time.
+func activeFilter(at time.Time) bson.M {
+	return bson.M{
+		"enabled":  true,
+		"startsAt": bson.M{"$lte": at},
+		"$or": bson.A{
+			bson.M{"endsAt": time.Time{}},
+			bson.M{"endsAt": bson.M{"$gt": at}},
+		},
+	}
+}
+
+func (r *ChallengeRepository) GetAllChallenges(ctx context.Context) ([]Challenge, error) {
+	return r.getChallenges(ctx, bson.M{})
+}
+
+func (r *ChallengeRepository) GetActiveChallenges(ctx context.Context, now time.Time) ([]Challenge, error) {
+	return r.getChallenges(ctx, activeFilter(now))
+}
+
+// GetChallengesForAction returns the challenges an action of the given
+// type at the given time counts towards.
+func (r *ChallengeRepository) GetChallengesForAction(ctx context.Context, actionType string, at time.Time) ([]Challenge, error) {
+	filter := activeFilter(at)
+	filter["actionType"] = actionType
+	return r.getChallenges(ctx, filter)
+}
+
+func (r *ChallengeRepository) getChallenges(ctx context.Context, filter bson.M) ([]Challenge, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "startsAt", Value: 1}})
+	cursor, err := r.challengeCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	challenges := []Challenge{}
+	if err := cursor.All(ctx, &challenges); err != nil {
+		return nil, err
+	}
+	return challenges, nil
+}
+
+func (r *ChallengeRepository) GetChallengeByID(ctx context.Context, id primitive.ObjectID) (*Challenge, error) {
+	var challenge Challenge
+	err := r.challengeCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&challenge)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrChallengeNotFound
+		}
+		return nil, err
+	}
+
+	return &challenge, nil
+}
+
+func (r *ChallengeRepository) AddChallenge(ctx context.Context, challenge *Challenge) (*Challenge, error) {
+	result, err := r.challengeCollection.InsertOne(ctx, challenge)
+	if err != nil {
+		return nil, err
+	}
+	generatedID, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return nil, errors.New("failed to convert InsertedID to ObjectID")
+	}
+	challenge.Id = generatedID
+	return challenge, nil
+}
+
+func (r *ChallengeRepository) UpdateChallenge(ctx context.Context, challenge *Challenge) (*Challenge, error) {
+	result, err := r.challengeCollection.ReplaceOne(ctx, bson.M{"_id": challenge.Id}, challenge)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, ErrChallengeNotFound
+	}
+	return challenge, nil
+}
+
+// DeleteChallenge removes the challenge. Users' progress is kept as a
+// record of rewards already given.
+func (r *ChallengeRepository) DeleteChallenge(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.challengeCollection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrChallengeNotFound
+	}
+	return nil
+}
+
+func (r *ChallengeRepository) GetProgress(ctx context.Context, challengeId primitive.ObjectID, userId primitive.ObjectID, windowStart time.Time) (*ChallengeProgress, error) {
+	var progress ChallengeProgress
+	filter := bson.M{"challengeId": challengeId, "userId": userId, "windowStart": windowStart}
+	err := r.progressCollection.FindOne(ctx, filter).Decode(&progress)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrChallengeProgressNotFound
+		}
+		return nil, err
+	}
+
+	return &progress, nil
+}
+
+// GetUserProgress returns the user's progress on the given challenges in
+// the windows containing now.
+func (r *ChallengeRepository) GetUserProgress(ctx context.Context, userId primitive.ObjectID, challengeIds []primitive.ObjectID, now time.Time) ([]ChallengeProgress, error) {
+	filter := bson.M{
+		"userId":      userId,
+		"challengeId": bson.M{"$in": challengeIds},
+		"windowStart": bson.M{"$lte": now},
+		"$or": bson.A{
+			bson.M{"windowEnd": time.Time{}},
+			bson.M{"windowEnd": bson.M{"$gt": now}},
+		},
+	}
+	cursor, err := r.progressCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	progress := []ChallengeProgress{}
+	if err := cursor.All(ctx, &progress); err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+// SaveProgress inserts new progress or replaces the stored one.
+func (r *ChallengeRepository) SaveProgress(ctx context.Context, progress *ChallengeProgress) (*ChallengeProgress, error) {
+	if progress.Id.IsZero() {
+		progress.Id = primitive.NewObjectID()
+	}
+	opts := options.Replace().SetUpsert(true)
+	if _, err := r.progressCollection.ReplaceOne(ctx, bson.M{"_id": progress.Id}, progress, opts); err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const dayLayout = "2006-01-02"
+
+// longestStreak returns the longest run of consecutive dates in days,
+// which must be sorted.
+func longestStreak(days []string) int {
+	longest, run := 0, 0
+	var previous time.Time
+	for _, day := range days {
+		date, err := time.Parse(dayLayout, day)
+		if err != nil {
+			continue
+		}
+		if run > 0 && date.Equal(previous.AddDate(0, 0, 1)) {
+			run++
+		} else {
+			run = 1
+		}
+		previous = date
+		longest = max(longest, run)
+	}
+	return longest
+}
+
+// record counts an action at the given time towards the progress.
+func (p *ChallengeProgress) record(kind string, at time.Time) {
+	if kind != ChallengeKindStreak {
+		p.Count++
+		return
+	}
+	day := at.In(cityLocation).Format(dayLayout)
+	if i, found := slices.BinarySearch(p.Days, day); !found {
+		p.Days = slices.Insert(p.Days, i, day)
+	}
+	p.Count = longestStreak(p.Days)
+}
+
+// advanceChallenges counts the action towards the user's challenges inside
+// the session's transaction and rewards the ones it completes. It returns
+// the progress that changed.
+func advanceChallenges(sessCtx mongo.SessionContext, challengeRepo *ChallengeRepository, benefitRepo *BenefitRepository, walletRepo *WalletRepository, leaderboardRepo *LeaderboardRepository, outbox *OutboxRepository, action *ActionEvent, now time.Time) ([]ChallengeProgress, error) {
+	challenges, err := challengeRepo.GetChallengesForAction(sessCtx, action.ActionType, action.OccurredAt)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := []ChallengeProgress{}
+	for _, challenge := range challenges {
+		windowStart, windowEnd := challenge.Window(action.OccurredAt)
+		progress, err := challengeRepo.GetProgress(sessCtx, challenge.Id, action.UserId, windowStart)
+		if err != nil {
+			if err != ErrChallengeProgressNotFound {
+				return nil, err
+			}
+			progress = &ChallengeProgress{
+				ChallengeId: challenge.Id,
+				UserId:      action.UserId,
+				WindowStart: windowStart,
+				WindowEnd:   windowEnd,
+			}
+		}
+		if progress.Completed {
+			continue
+		}
+
+		progress.Target = challenge.Target
+		progress.record(challenge.Kind, action.OccurredAt)
+		progress.UpdatedAt = now
+		if progress.Count >= challenge.Target {
+			if err := rewardChallenge(sessCtx, benefitRepo, walletRepo, leaderboardRepo, outbox, &challenge, progress, now); err != nil {
+				return nil, err
+			}
+		}
+
+		savedProgress, err := challengeRepo.SaveProgress(sessCtx, progress)
+		if err != nil {
+			return nil, err
+		}
+		updated = append(updated, *savedProgress)
+	}
+	return updated, nil
+}
+
+// rewardChallenge marks the progress completed and gives the user the
+// challenge's reward. A benefit that cannot be given any more, e.g. because
+// it is out of stock, is noted on the progress instead of failing the
+// action; the tokens are still granted.
+func rewardChallenge(sessCtx mongo.SessionContext, benefitRepo *BenefitRepository, walletRepo *WalletRepository, leaderboardRepo *LeaderboardRepository, outbox *OutboxRepository, challenge *Challenge, progress *ChallengeProgress, now time.Time) error {
+	progress.Completed = true
+	progress.CompletedAt = &now
+	events := []Event{}
+
+	if challenge.RewardTokens > 0 {
+		wallet, err := walletRepo.GetWalletByUserID(sessCtx, progress.UserId)
+		if err != nil {
+			return err
+		}
+		err = creditWallet(sessCtx, walletRepo, leaderboardRepo, outbox, wallet, TokensGrantedPayload{
+			Amount:      challenge.RewardTokens,
+			Source:      TokenSourceChallenge,
+			ActionType:  challenge.ActionType,
+			ChallengeId: &challenge.Id,
+		}, now)
+		if err != nil {
+			return err
+		}
+		progress.RewardTokens = challenge.RewardTokens
+	}
+
+	if challenge.RewardBenefitId != nil {
+		ownedBenefit, err := giveFreeBenefit(sessCtx, benefitRepo, challenge, progress, now)
+		switch {
+		case errors.Is(err, ErrBenefitNotFound), errors.Is(err, ErrBenefitUnavailable), errors.Is(err, ErrOutOfStock):
+			progress.RewardError = err.Error()
+		case err != nil:
+			return err
+		default:
+			progress.RewardBenefitId = &ownedBenefit.BenefitId
+			progress.RewardOwnedBenefitId = &ownedBenefit.Id
+			events = append(events, benefitPurchasedEvents([]OwnedBenefit{*ownedBenefit})...)
+		}
+	}
+
+	events = append(events, NewEvent(EventChallengeCompleted, challenge.Id, ChallengeCompletedPayload{
+		ChallengeId:          challenge.Id,
+		UserId:               progress.UserId,
+		WindowStart:          progress.WindowStart,
+		RewardTokens:         progress.RewardTokens,
+		RewardOwnedBenefitId: progress.RewardOwnedBenefitId,
+		CompletedAt:          now,
+	}))
+	return outbox.Add(sessCtx, events...)
+}
+
+// giveFreeBenefit takes one unit of the challenge's reward benefit out of
+// stock and gives it to the user at no cost.
+func giveFreeBenefit(sessCtx mongo.SessionContext, benefitRepo *BenefitRepository, challenge *Challenge, progress *ChallengeProgress, now time.Time) (*OwnedBenefit, error) {
+	benefit, err := benefitRepo.GetBenefitByID(sessCtx, *challenge.RewardBenefitId)
+	if err != nil {
+		return nil, err
+	}
+	if !benefit.IsAvailable(now) {
+		return nil, ErrBenefitUnavailable
+	}
+	if err := benefitRepo.DecrementStock(sessCtx, benefit.Id, 1); err != nil {
+		return nil, err
+	}
+	voucherCode, err := generateVoucherCode()
+	if err != nil {
+		return nil, err
+	}
+	return benefitRepo.AddPurchasedBenefit(sessCtx, &OwnedBenefit{
+		OwnerId:        progress.UserId,
+		BenefitId:      benefit.Id,
+		Purchased:      now,
+		Content:        voucherCode,
+		VoucherCode:    voucherCode,
+		ExpirationDate: benefit.ExpirationDate,
+		PricePaid:      0,
+		PartnerId:      benefit.PartnerId,
+	})
+}
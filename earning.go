@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// Actions older than this are rejected so caps cannot be dodged by
+	// back-dating events.
+	maxActionAge = 7 * 24 * time.Hour
+	// Reporters' clocks may run slightly ahead of ours.
+	maxActionClockSkew = 5 * time.Minute
+)
+
+var ErrActionTimeInvalid = errors.New("action must have occurred within the last 7 days")
+
+// capWindowStarts returns the start of the calendar day and of the week,
+// starting on Monday, that contain at in the city's time zone.
+func capWindowStarts(at time.Time) (time.Time, time.Time) {
+	local := at.In(cityLocation)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, cityLocation)
+	sinceMonday := (int(day.Weekday()) + 6) % 7
+	return day, day.AddDate(0, 0, -sinceMonday)
+}
+
+// earnTokens applies the earning rules to an action inside the session's
+// transaction: it works out what each valid rule grants under its caps,
+// credits the total to the user's wallet and the leaderboards, records the
+// action and adds a TokensGranted event to the outbox. A repeated event ID fails with
+// ErrDuplicateAction and grants nothing.
+func earnTokens(sessCtx mongo.SessionContext, earningRepo *EarningRepository, walletRepo *WalletRepository, leaderboardRepo *LeaderboardRepository, outbox *OutboxRepository, action *ActionEvent, now time.Time) (*ActionEvent, error) {
+	if action.OccurredAt.Before(now.Add(-maxActionAge)) || action.OccurredAt.After(now.Add(maxActionClockSkew)) {
+		return nil, ErrActionTimeInvalid
+	}
+
+	// Step 1: Make sure the user has a wallet
+	wallet, err := walletRepo.GetWalletByUserID(sessCtx, action.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 2: Apply every rule valid when the action happened, within its caps
+	rules, err := earningRepo.GetRulesForAction(sessCtx, action.ActionType)
+	if err != nil {
+		return nil, err
+	}
+	dayStart, weekStart := capWindowStarts(action.OccurredAt)
+	action.Grants = []RuleGrant{}
+	action.TokensGranted = 0
+	for _, rule := range rules {
+		if !rule.IsValidAt(action.OccurredAt) {
+			continue
+		}
+		grant := RuleGrant{RuleId: rule.Id, Tokens: rule.Tokens}
+		caps := []struct {
+			limit    int
+			from, to time.Time
+		}{{rule.DailyCap, dayStart, dayStart.AddDate(0, 0, 1)}, {rule.WeeklyCap, weekStart, weekStart.AddDate(0, 0, 7)}}
+		for _, limit := range caps {
+			if limit.limit <= 0 {
+				continue
+			}
+			earned, err := earningRepo.TokensEarned(sessCtx, action.UserId, rule.Id, limit.from, limit.to)
+			if err != nil {
+				return nil, err
+			}
+			if remaining := max(limit.limit-earned, 0); remaining < grant.Tokens {
+				grant.Tokens = remaining
+				grant.Capped = true
+			}
+		}
+		action.Grants = append(action.Grants, grant)
+		action.TokensGranted += grant.Tokens
+	}
+
+	// Step 3: Record the action; a repeated event ID stops here
+	action.ReceivedAt = now
+	savedAction, err := earningRepo.AddAction(sessCtx, action)
+	if err != nil {
+		return nil, err
+	}
+	if savedAction.TokensGranted == 0 {
+		return savedAction, nil
+	}
+
+	// Step 4: Credit the wallet and let other services know
+	err = creditWallet(sessCtx, walletRepo, leaderboardRepo, outbox, wallet, TokensGrantedPayload{
+		Amount:     savedAction.TokensGranted,
+		Source:     TokenSourceAction,
+		ActionType: savedAction.ActionType,
+	}, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return savedAction, nil
+}
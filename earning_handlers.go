@@ -0,0 +1,315 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ActionResult is the outcome of reporting an action. Duplicate is set
+// when the event ID was reported before; the earlier action is returned
+// and nothing is granted again. Challenges lists the challenge progress
+// the action advanced and Referral the referral it completed.
+type ActionResult struct {
+	Action     ActionEvent         `json:"action"`
+	Challenges []ChallengeProgress `json:"challenges,omitempty"`
+	Referral   *Referral           `json:"referral,omitempty"`
+	Duplicate  bool                `json:"duplicate"`
+}
+
+// @Summary Get earning rules
+// @Description Retrieves all token earning rules
+// @Tags earning
+// @Accept json
+// @Produce json
+// @Success 200 {array} EarningRule "List of earning rules"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /earning-rules [get]
+func getEarningRules(c *gin.Context, repo *EarningRepository) {
+	rules, err := repo.GetAllRules(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+// @Summary Get a single earning rule
+// @Description Retrieves a token earning rule by its ID
+// @Tags earning
+// @Accept json
+// @Produce json
+// @Param id path string true "Earning rule ID"
+// @Success 200 {object} EarningRule "Single earning rule"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 404 {object} ErrorResponse "Earning rule not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /earning-rules/{id} [get]
+func getEarningRule(c *gin.Context, repo *EarningRepository) {
+	ruleId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := repo.GetRuleByID(c.Request.Context(), ruleId)
+	if err != nil {
+		if err == ErrEarningRuleNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+// @Summary Add an earning rule
+// @Description Adds a rule granting tokens for an action type, with optional daily and weekly caps per user and a validity period
+// @Tags earning
+// @Accept json
+// @Produce json
+// @Param rule body EarningRule true "Earning rule to add"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 201 {object} EarningRule "Earning rule created"
+// @Failure 400 {object} ErrorResponse "Invalid earning rule"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /earning-rules [post]
+func addEarningRule(c *gin.Context, repo *EarningRepository) {
+	var rule EarningRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := rule.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule.Id = primitive.NilObjectID
+	savedRule, err := repo.AddRule(c.Request.Context(), &rule)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAuditChange(c, "earning-rules", savedRule.Id, nil, savedRule)
+	c.JSON(http.StatusCreated, savedRule)
+}
+
+// @Summary Update an earning rule
+// @Description Updates a token earning rule by its ID. Tokens already granted are not affected.
+// @Tags earning
+// @Accept json
+// @Produce json
+// @Param id path string true "Earning rule ID"
+// @Param rule body EarningRule true "Updated earning rule"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 200 {object} EarningRule "Earning rule updated successfully"
+// @Failure 400 {object} ErrorResponse "Invalid ID or earning rule"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 404 {object} ErrorResponse "Earning rule not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /earning-rules/{id} [put]
+func updateEarningRule(c *gin.Context, repo *EarningRepository) {
+	ruleId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	existingRule, err := repo.GetRuleByID(c.Request.Context(), ruleId)
+	if err != nil {
+		if err == ErrEarningRuleNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var rule EarningRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if rule.Id != ruleId {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Earning rule ID in URL does not match ID in request body"})
+		return
+	}
+	if err := rule.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	savedRule, err := repo.UpdateRule(c.Request.Context(), &rule)
+	if err != nil {
+		if err == ErrEarningRuleNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAuditChange(c, "earning-rules", ruleId, existingRule, savedRule)
+	c.JSON(http.StatusOK, savedRule)
+}
+
+// @Summary Delete an earning rule
+// @Description Deletes a token earning rule by its ID
+// @Tags earning
+// @Accept json
+// @Produce json
+// @Param id path string true "Earning rule ID"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 200 {object} object "Earning rule deleted successfully"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 404 {object} ErrorResponse "Earning rule not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /earning-rules/{id} [delete]
+func deleteEarningRule(c *gin.Context, repo *EarningRepository) {
+	ruleId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deletedRule, err := repo.GetRuleByID(c.Request.Context(), ruleId)
+	if err != nil {
+		if err == ErrEarningRuleNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := repo.DeleteRule(c.Request.Context(), ruleId); err != nil {
+		if err == ErrEarningRuleNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAuditChange(c, "earning-rules", ruleId, deletedRule, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Earning rule deleted successfully"})
+}
+
+// @Summary Report an action
+// @Description Reports a civic action of a user, grants tokens according to the earning rules, advances the user's challenges and pays referral bonuses on a referred user's first qualifying action. Reporting the same event_id again grants nothing and returns the original outcome.
+// @Tags earning
+// @Accept json
+// @Produce json
+// @Param event_id body string true "Reporter's unique ID of the action"
+// @Param user_id body string true "User ID"
+// @Param action_type body string true "Action type, e.g. public-transport"
+// @Param occurred_at body string false "When the action happened, defaults to now, at most 7 days ago"
+// @Param source body string false "Reporting system"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 201 {object} ActionResult "Action processed"
+// @Success 200 {object} ActionResult "Action was already processed"
+// @Failure 400 {object} ErrorResponse "Invalid request or action time"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 404 {object} ErrorResponse "Wallet not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /actions [post]
+func reportAction(c *gin.Context, earningRepo *EarningRepository, challengeRepo *ChallengeRepository, benefitRepo *BenefitRepository, walletRepo *WalletRepository, leaderboardRepo *LeaderboardRepository, referralRepo *ReferralRepository, outbox *OutboxRepository, live *LiveUpdates) {
+	ctx := c.Request.Context()
+	var req struct {
+		EventId    string             `json:"event_id"`
+		UserID     primitive.ObjectID `json:"user_id"`
+		ActionType string             `json:"action_type"`
+		OccurredAt time.Time          `json:"occurred_at"`
+		Source     string             `json:"source"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.EventId == "" || req.ActionType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "event_id and action_type are required"})
+		return
+	}
+	now := time.Now()
+	if req.OccurredAt.IsZero() {
+		req.OccurredAt = now
+	}
+
+	session, err := walletRepo.collection.Database().Client().StartSession()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start session"})
+		return
+	}
+	defer session.EndSession(ctx)
+
+	transaction := func(sessCtx mongo.SessionContext) (any, error) {
+		action, err := earnTokens(sessCtx, earningRepo, walletRepo, leaderboardRepo, outbox, &ActionEvent{
+			EventId:    req.EventId,
+			UserId:     req.UserID,
+			ActionType: req.ActionType,
+			Source:     req.Source,
+			OccurredAt: req.OccurredAt,
+		}, now)
+		if err != nil {
+			return nil, err
+		}
+		challenges, err := advanceChallenges(sessCtx, challengeRepo, benefitRepo, walletRepo, leaderboardRepo, outbox, action, now)
+		if err != nil {
+			return nil, err
+		}
+		referral, err := completeReferral(sessCtx, referralRepo, walletRepo, leaderboardRepo, outbox, action, now)
+		if err != nil {
+			return nil, err
+		}
+		return &ActionResult{Action: *action, Challenges: challenges, Referral: referral}, nil
+	}
+
+	result, err := session.WithTransaction(ctx, transaction)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrDuplicateAction):
+			action, err := earningRepo.GetActionByEventID(ctx, req.EventId)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, ActionResult{Action: *action, Duplicate: true})
+		case errors.Is(err, ErrWalletNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrActionTimeInvalid):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	actionResult := result.(*ActionResult)
+	walletChanged := actionResult.Action.TokensGranted > 0
+	for _, progress := range actionResult.Challenges {
+		walletChanged = walletChanged || progress.RewardTokens > 0
+		if progress.RewardBenefitId != nil {
+			live.StockChanged(ctx, *progress.RewardBenefitId)
+		}
+	}
+	if referral := actionResult.Referral; referral != nil {
+		walletChanged = walletChanged || referral.RefereeBonus > 0
+		if referral.ReferrerBonus > 0 {
+			live.WalletChanged(ctx, referral.ReferrerId)
+		}
+	}
+	if walletChanged {
+		live.WalletChanged(ctx, actionResult.Action.UserId)
+	}
+	c.JSON(http.StatusCreated, actionResult)
+}
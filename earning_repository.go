@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrEarningRuleNotFound = errors.New("earning rule not found")
+var ErrInvalidEarningRule = errors.New("invalid earning rule")
+var ErrDuplicateAction = errors.New("action event was already processed")
+
+// EarningRule grants tokens for a civic action such as using public
+// transport. Caps limit the tokens a single user can earn from the rule per
+// calendar day and per week starting on Monday; zero means no cap.
+type EarningRule struct {
+	Id          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	ActionType  string             `json:"actionType" bson:"actionType" example:"public-transport"`
+	Name        string             `json:"name" bson:"name"`
+	Description string             `json:"description" bson:"description"`
+	Tokens      int                `json:"tokens" bson:"tokens"`
+	DailyCap    int                `json:"dailyCap" bson:"dailyCap"`
+	WeeklyCap   int                `json:"weeklyCap" bson:"weeklyCap"`
+	ValidFrom   time.Time          `json:"validFrom" bson:"validFrom"`
+	ValidUntil  time.Time          `json:"validUntil" bson:"validUntil"`
+	Enabled     bool               `json:"enabled" bson:"enabled"`
+}
+
+func (r *EarningRule) Validate() error {
+	if !slugPattern.MatchString(r.ActionType) {
+		return fmt.Errorf("%w: actionType must be lowercase letters, digits and dashes", ErrInvalidEarningRule)
+	}
+	if r.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidEarningRule)
+	}
+	if r.Tokens <= 0 {
+		return fmt.Errorf("%w: tokens must be positive", ErrInvalidEarningRule)
+	}
+	if r.DailyCap < 0 || r.WeeklyCap < 0 {
+		return fmt.Errorf("%w: caps must not be negative", ErrInvalidEarningRule)
+	}
+	if !r.ValidFrom.IsZero() && !r.ValidUntil.IsZero() && !r.ValidUntil.After(r.ValidFrom) {
+		return fmt.Errorf("%w: validUntil must be after validFrom", ErrInvalidEarningRule)
+	}
+	return nil
+}
+
+// IsValidAt reports whether the rule applies to an action at the given time.
+func (r *EarningRule) IsValidAt(at time.Time) bool {
+	if !r.Enabled {
+		return false
+	}
+	if !r.ValidFrom.IsZero() && at.Before(r.ValidFrom) {
+		return false
+	}
+	return r.ValidUntil.IsZero() || at.Before(r.ValidUntil)
+}
+
+// RuleGrant is what one rule granted for an action.
+type RuleGrant struct {
+	RuleId primitive.ObjectID `json:"ruleId" bson:"ruleId"`
+	Tokens int                `json:"tokens" bson:"tokens"`
+	Capped bool               `json:"capped" bson:"capped"`
+}
+
+// ActionEvent is a civic action reported by another service. EventId is
+// chosen by the reporter and makes repeated reports of the same action
+// harmless.
+type ActionEvent struct {
+	Id            primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	EventId       string             `json:"eventId" bson:"eventId"`
+	UserId        primitive.ObjectID `json:"userId" bson:"userId"`
+	ActionType    string             `json:"actionType" bson:"actionType"`
+	Source        string             `json:"source,omitempty" bson:"source,omitempty"`
+	OccurredAt    time.Time          `json:"occurredAt" bson:"occurredAt"`
+	ReceivedAt    time.Time          `json:"receivedAt" bson:"receivedAt"`
+	Grants        []RuleGrant        `json:"grants" bson:"grants"`
+	TokensGranted int                `json:"tokensGranted" bson:"tokensGranted"`
+}
+
+type EarningRepository struct {
+	ruleCollection   *mongo.Collection
+	actionCollection *mongo.Collection
+}
+
+func NewEarningRepository(ruleCollection *mongo.Collection, actionCollection *mongo.Collection) (*EarningRepository, error) {
+	_, err := actionCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "eventId", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "grants.ruleId", Value: 1}, {Key: "occurredAt", Value: 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &EarningRepository{
+		ruleCollection:   ruleCollection,
+		actionCollection: actionCollection,
+	}, nil
+}
+
+func (r *EarningRepository) GetAllRules(ctx context.Context) ([]EarningRule, error) {
+	return r.getRules(ctx, bson.M{})
+}
+
+// GetRulesForAction returns the enabled rules for the action type.
+func (r *EarningRepository) GetRulesForAction(ctx context.Context, actionType string) ([]EarningRule, error) {
+	return r.getRules(ctx, bson.M{"actionType": actionType, "enabled": true})
+}
+
+func (r *EarningRepository) getRules(ctx context.Context, filter bson.M) ([]EarningRule, error) {
+	cursor, err := r.ruleCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	rules := []EarningRule{}
+	if err := cursor.All(ctx, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (r *EarningRepository) GetRuleByID(ctx context.Context, id primitive.ObjectID) (*EarningRule, error) {
+	var rule EarningRule
+	err := r.ruleCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&rule)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrEarningRuleNotFound
+		}
+		return nil, err
+	}
+
+	return &rule, nil
+}
+
+func (r *EarningRepository) AddRule(ctx context.Context, rule *EarningRule) (*EarningRule, error) {
+	result, err := r.ruleCollection.InsertOne(ctx, rule)
+	if err != nil {
+		return nil, err
+	}
+	generatedID, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return nil, errors.New("failed to convert InsertedID to ObjectID")
+	}
+	rule.Id = generatedID
+	return rule, nil
+}
+
+func (r *EarningRepository) UpdateRule(ctx context.Context, rule *EarningRule) (*EarningRule, error) {
+	result, err := r.ruleCollection.ReplaceOne(ctx, bson.M{"_id": rule.Id}, rule)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, ErrEarningRuleNotFound
+	}
+	return rule, nil
+}
+
+func (r *EarningRepository) DeleteRule(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.ruleCollection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrEarningRuleNotFound
+	}
+	return nil
+}
+
+// TokensEarned sums the tokens the user earned from the rule for actions
+// that occurred at or after from and before to.
+func (r *EarningRepository) TokensEarned(ctx context.Context, userId primitive.ObjectID, ruleId primitive.ObjectID, from time.Time, to time.Time) (int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"userId": userId, "grants.ruleId": ruleId, "occurredAt": bson.M{"$gte": from, "$lt": to}}}},
+		{{Key: "$unwind", Value: "$grants"}},
+		{{Key: "$match", Value: bson.M{"grants.ruleId": ruleId}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "tokens": bson.M{"$sum": "$grants.tokens"}}}},
+	}
+	cursor, err := r.actionCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var totals []struct {
+		Tokens int `bson:"tokens"`
+	}
+	if err := cursor.All(ctx, &totals); err != nil {
+		return 0, err
+	}
+	if len(totals) == 0 {
+		return 0, nil
+	}
+	return totals[0].Tokens, nil
+}
+
+// AddAction stores a processed action. It fails with ErrDuplicateAction if
+// an action with the same event ID exists.
+func (r *EarningRepository) AddAction(ctx context.Context, action *ActionEvent) (*ActionEvent, error) {
+	result, err := r.actionCollection.InsertOne(ctx, action)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrDuplicateAction
+		}
+		return nil, err
+	}
+	generatedID, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return nil, errors.New("failed to convert InsertedID to ObjectID")
+	}
+	action.Id = generatedID
+	return action, nil
+}
+
+func (r *EarningRepository) GetActionByEventID(ctx context.Context, eventId string) (*ActionEvent, error) {
+	var action ActionEvent
+	err := r.actionCollection.FindOne(ctx, bson.M{"eventId": eventId}).Decode(&action)
+	if err != nil {
+		return nil, err
+	}
+	return &action, nil
+}
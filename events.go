@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	EventBenefitExpired      = "BenefitExpired"
+	EventOwnedBenefitExpired = "OwnedBenefitExpired"
+	EventBenefitPurchased    = "BenefitPurchased"
+	EventBenefitRedeemed     = "BenefitRedeemed"
+	EventTokensGranted       = "TokensGranted"
+	EventTokensExpired       = "TokensExpired"
+	EventChallengeCompleted  = "ChallengeCompleted"
+	EventReferralCompleted   = "ReferralCompleted"
+)
+
+// Event describes a state change other parts of the system may react to.
+type Event struct {
+	Id          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Type        string             `json:"type" bson:"type"`
+	AggregateId primitive.ObjectID `json:"aggregateId" bson:"aggregateId"`
+	OccurredAt  time.Time          `json:"occurredAt" bson:"occurredAt"`
+	Payload     any                `json:"payload" bson:"payload"`
+}
+
+func NewEvent(eventType string, aggregateId primitive.ObjectID, payload any) Event {
+	return Event{
+		Id:          primitive.NewObjectID(),
+		Type:        eventType,
+		AggregateId: aggregateId,
+		OccurredAt:  time.Now(),
+		Payload:     payload,
+	}
+}
+
+// BenefitPurchasedPayload describes one purchased unit. The voucher code
+// is left out because it must stay with the owner.
+type BenefitPurchasedPayload struct {
+	OwnedBenefitId   primitive.ObjectID  `json:"ownedBenefitId" bson:"ownedBenefitId"`
+	BenefitId        primitive.ObjectID  `json:"benefitId" bson:"benefitId"`
+	UserId           primitive.ObjectID  `json:"userId" bson:"userId"`
+	PricePaid        int                 `json:"pricePaid" bson:"pricePaid"`
+	PromoCode        string              `json:"promoCode,omitempty" bson:"promoCode,omitempty"`
+	PartnerId        *primitive.ObjectID `json:"partnerId,omitempty" bson:"partnerId,omitempty"`
+	BundlePurchaseId *primitive.ObjectID `json:"bundlePurchaseId,omitempty" bson:"bundlePurchaseId,omitempty"`
+	CheckoutId       *primitive.ObjectID `json:"checkoutId,omitempty" bson:"checkoutId,omitempty"`
+	PurchasedAt      time.Time           `json:"purchasedAt" bson:"purchasedAt"`
+}
+
+type BenefitRedeemedPayload struct {
+	OwnedBenefitId primitive.ObjectID  `json:"ownedBenefitId" bson:"ownedBenefitId"`
+	BenefitId      primitive.ObjectID  `json:"benefitId" bson:"benefitId"`
+	UserId         primitive.ObjectID  `json:"userId" bson:"userId"`
+	PartnerId      *primitive.ObjectID `json:"partnerId,omitempty" bson:"partnerId,omitempty"`
+	RedeemedAt     time.Time           `json:"redeemedAt" bson:"redeemedAt"`
+}
+
+// TokensGrantedPayload tells whether tokens were granted by hand, earned
+// for an action, won in a challenge or paid as a referral bonus, and for
+// which action type.
+type TokensGrantedPayload struct {
+	UserId      primitive.ObjectID  `json:"userId" bson:"userId"`
+	Amount      int                 `json:"amount" bson:"amount"`
+	ExpiresAt   time.Time           `json:"expiresAt" bson:"expiresAt"`
+	Source      string              `json:"source" bson:"source"`
+	ActionType  string              `json:"actionType,omitempty" bson:"actionType,omitempty"`
+	ChallengeId *primitive.ObjectID `json:"challengeId,omitempty" bson:"challengeId,omitempty"`
+	ReferralId  *primitive.ObjectID `json:"referralId,omitempty" bson:"referralId,omitempty"`
+}
+
+type ChallengeCompletedPayload struct {
+	ChallengeId          primitive.ObjectID  `json:"challengeId" bson:"challengeId"`
+	UserId               primitive.ObjectID  `json:"userId" bson:"userId"`
+	WindowStart          time.Time           `json:"windowStart" bson:"windowStart"`
+	RewardTokens         int                 `json:"rewardTokens" bson:"rewardTokens"`
+	RewardOwnedBenefitId *primitive.ObjectID `json:"rewardOwnedBenefitId,omitempty" bson:"rewardOwnedBenefitId,omitempty"`
+	CompletedAt          time.Time           `json:"completedAt" bson:"completedAt"`
+}
+
+type ReferralCompletedPayload struct {
+	ReferralId     primitive.ObjectID `json:"referralId" bson:"referralId"`
+	ReferrerId     primitive.ObjectID `json:"referrerId" bson:"referrerId"`
+	RefereeId      primitive.ObjectID `json:"refereeId" bson:"refereeId"`
+	ReferrerBonus  int                `json:"referrerBonus" bson:"referrerBonus"`
+	RefereeBonus   int                `json:"refereeBonus" bson:"refereeBonus"`
+	ReferrerCapped bool               `json:"referrerCapped" bson:"referrerCapped"`
+	CompletedAt    time.Time          `json:"completedAt" bson:"completedAt"`
+}
+
+type TokensExpiredPayload struct {
+	UserId    primitive.ObjectID `json:"userId" bson:"userId"`
+	LotId     primitive.ObjectID `json:"lotId" bson:"lotId"`
+	Amount    int                `json:"amount" bson:"amount"`
+	ExpiredAt time.Time          `json:"expiredAt" bson:"expiredAt"`
+}
+
+// benefitPurchasedEvents returns one BenefitPurchased event per owned benefit.
+func benefitPurchasedEvents(ownedBenefits []OwnedBenefit) []Event {
+	events := make([]Event, 0, len(ownedBenefits))
+	for _, ownedBenefit := range ownedBenefits {
+		events = append(events, NewEvent(EventBenefitPurchased, ownedBenefit.Id, BenefitPurchasedPayload{
+			OwnedBenefitId:   ownedBenefit.Id,
+			BenefitId:        ownedBenefit.BenefitId,
+			UserId:           ownedBenefit.OwnerId,
+			PricePaid:        ownedBenefit.PricePaid,
+			PromoCode:        ownedBenefit.PromoCode,
+			PartnerId:        ownedBenefit.PartnerId,
+			BundlePurchaseId: ownedBenefit.BundlePurchaseId,
+			CheckoutId:       ownedBenefit.CheckoutId,
+			PurchasedAt:      ownedBenefit.Purchased,
+		}))
+	}
+	return events
+}
+
+type EventPublisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// LogEventPublisher writes events to the service log.
+type LogEventPublisher struct{}
+
+func (LogEventPublisher) Publish(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event.Payload)
+	if err != nil {
+		return err
+	}
+	log.Printf("event %s for %s: %s", event.Type, event.AggregateId.Hex(), payload)
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const expirationJobLock = "benefit-expiration-job"
+
+// ExpirationJob periodically marks benefits and owned benefits whose
+// expiration date has passed as expired. Only the replica holding the job
+// lock does the work. Each flag is set in the same transaction as the
+// outbox event announcing it, so no event is lost.
+type ExpirationJob struct {
+	benefitRepo *BenefitRepository
+	lock        *MongoLock
+	outbox      *OutboxRepository
+	interval    time.Duration
+}
+
+func NewExpirationJob(benefitRepo *BenefitRepository, lock *MongoLock, outbox *OutboxRepository, interval time.Duration) *ExpirationJob {
+	return &ExpirationJob{
+		benefitRepo: benefitRepo,
+		lock:        lock,
+		outbox:      outbox,
+		interval:    interval,
+	}
+}
+
+// Run executes the job once immediately and then on every tick until ctx is
+// cancelled.
+func (j *ExpirationJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		j.runOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *ExpirationJob) runOnce(ctx context.Context) {
+	// The lease outlives one interval so the leader keeps it between runs
+	acquired, err := j.lock.TryAcquire(ctx, expirationJobLock, 2*j.interval)
+	if err != nil {
+		log.Printf("expiration job failed to acquire lock: %v", err)
+		return
+	}
+	if !acquired {
+		return
+	}
+
+	now := time.Now()
+	if err := j.expireBenefits(ctx, now); err != nil {
+		log.Printf("failed to expire benefits: %v", err)
+	}
+	if err := j.expireOwnedBenefits(ctx, now); err != nil {
+		log.Printf("failed to expire owned benefits: %v", err)
+	}
+}
+
+func (j *ExpirationJob) expireBenefits(ctx context.Context, now time.Time) error {
+	benefits, err := j.benefitRepo.GetBenefitsExpiredBy(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for _, benefit := range benefits {
+		err := j.inTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+			marked, err := j.benefitRepo.MarkBenefitExpired(sessCtx, benefit.Id)
+			if err != nil || !marked {
+				return err
+			}
+			benefit.Expired = true
+			return j.outbox.Add(sessCtx, NewEvent(EventBenefitExpired, benefit.Id, benefit))
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (j *ExpirationJob) expireOwnedBenefits(ctx context.Context, now time.Time) error {
+	ownedBenefits, err := j.benefitRepo.GetOwnedBenefitsExpiredBy(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for _, ownedBenefit := range ownedBenefits {
+		err := j.inTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+			marked, err := j.benefitRepo.MarkOwnedBenefitExpired(sessCtx, ownedBenefit.Id)
+			if err != nil || !marked {
+				return err
+			}
+			ownedBenefit.Expired = true
+			return j.outbox.Add(sessCtx, NewEvent(EventOwnedBenefitExpired, ownedBenefit.Id, ownedBenefit))
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (j *ExpirationJob) inTransaction(ctx context.Context, fn func(sessCtx mongo.SessionContext) error) error {
+	session, err := j.benefitRepo.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (any, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
 
@@ -21,15 +23,19 @@ type ErrorResponse struct {
 // @Tags benefits
 // @Accept json
 // @Produce json
-// @Param category query string false "Category of the benefit"
+// @Param category query string false "Category slug, also matches its subcategories"
 // @Param min_price query string false "Minimum price of the benefit"
 // @Param max_price query string false "Maximum price of the benefit"
-// @Param search query string false "Search term"
+// @Param search query string false "Search term, matched against the name in the requested language"
+// @Param lang query string false "Language of localizedName and localizedDescription, overrides Accept-Language"
+// @Param Accept-Language header string false "Preferred languages"
+// @Param partner_id query string false "Only benefits of this partner"
+// @Param coming_soon query bool false "Also list benefits that are not available yet"
 // @Success 200 {array} Benefit "List of benefits"
 // @Failure 400 {object} ErrorResponse "Invalid query parameters"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /benefits [get]
-func getBenefits(c *gin.Context, repo *BenefitRepository) {
+func getBenefits(c *gin.Context, repo *BenefitRepository, categoryRepo *CategoryRepository) {
 	ctx := c.Request.Context()
 
 	// Get query parameters
@@ -37,13 +43,19 @@ func getBenefits(c *gin.Context, repo *BenefitRepository) {
 	minPrice := c.Query("min_price")
 	maxPrice := c.Query("max_price")
 	search := c.Query("search")
+	partnerId := c.Query("partner_id")
+	comingSoon := c.Query("coming_soon") == "true"
+	locale := requestLocale(c)
 
 	// Prepare filter options
 	filter := bson.M{}
 	if category != "" {
-		if category != "" {
-			filter["category"] = bson.M{"$regex": category, "$options": "i"}
+		slugs, err := categoryRepo.GetDescendantSlugs(ctx, category)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
 		}
+		filter["category"] = bson.M{"$in": slugs}
 	}
 	if minPrice != "" {
 		minPriceFloat, err := strconv.ParseFloat(minPrice, 64)
@@ -62,7 +74,17 @@ func getBenefits(c *gin.Context, repo *BenefitRepository) {
 		filter["price"] = bson.M{"$lte": maxPriceFloat}
 	}
 	if search != "" {
-		filter["name"] = bson.M{"$regex": search, "$options": "i"}
+		for key, value := range localizedSearchFilter(search, locale) {
+			filter[key] = value
+		}
+	}
+	if partnerId != "" {
+		partnerObjectId, err := primitive.ObjectIDFromHex(partnerId)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid partner_id"})
+			return
+		}
+		filter["partnerId"] = partnerObjectId
 	}
 
 	// Get benefits based on filter
@@ -72,6 +94,12 @@ func getBenefits(c *gin.Context, repo *BenefitRepository) {
 		return
 	}
 
+	benefits = filterAvailable(benefits, time.Now(), comingSoon)
+	for i := range benefits {
+		benefits[i].Localize(locale)
+	}
+
+	c.Header("Vary", "Accept-Language")
 	c.JSON(http.StatusOK, benefits)
 }
 
@@ -81,6 +109,8 @@ func getBenefits(c *gin.Context, repo *BenefitRepository) {
 // @Accept json
 // @Produce json
 // @Param id path string true "Benefit ID"
+// @Param lang query string false "Language of localizedName and localizedDescription, overrides Accept-Language"
+// @Param Accept-Language header string false "Preferred languages"
 // @Success 200 {object} Benefit "Single benefit"
 // @Failure 400 {object} ErrorResponse "Invalid ID format"
 // @Failure 404 {object} ErrorResponse "Benefit not found"
@@ -100,6 +130,8 @@ func getBenefit(c *gin.Context, repo *BenefitRepository) {
 		return
 	}
 
+	benefit.Localize(requestLocale(c))
+	c.Header("Vary", "Accept-Language")
 	c.JSON(http.StatusOK, benefit)
 }
 
@@ -109,22 +141,42 @@ func getBenefit(c *gin.Context, repo *BenefitRepository) {
 // @Accept json
 // @Produce json
 // @Param benefit body Benefit true "Benefit to add"
+// @Param X-Api-Key header string true "API key of a calling backend"
 // @Success 201 {object} Benefit "Benefit created"
-// @Failure 400 {object} ErrorResponse "Invalid benefit format"
+// @Failure 400 {object} ErrorResponse "Invalid benefit format or unknown category"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /benefits [post]
-func addBenefit(c *gin.Context, repo *BenefitRepository) {
+func addBenefit(c *gin.Context, repo *BenefitRepository, categoryRepo *CategoryRepository) {
 	ctx := c.Request.Context()
 	var benefit Benefit
 	if err := c.ShouldBindJSON(&benefit); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := benefit.ValidateSchedule(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := benefit.ValidateTranslations(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := benefit.ValidateLimits(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateBenefitCategory(ctx, categoryRepo, &benefit); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	savedBenefit, err := repo.AddBenefit(ctx, &benefit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	recordAuditChange(c, "benefits", savedBenefit.Id, nil, savedBenefit)
 	c.JSON(http.StatusCreated, savedBenefit)
 }
 
@@ -134,8 +186,11 @@ func addBenefit(c *gin.Context, repo *BenefitRepository) {
 // @Accept json
 // @Produce json
 // @Param id path string true "Benefit ID"
+// @Param X-Api-Key header string true "API key of a calling backend"
 // @Success 200 {object} object "Benefit deleted successfully"
 // @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /benefits/{id} [delete]
 func deleteBenefit(c *gin.Context, repo *BenefitRepository) {
@@ -147,11 +202,20 @@ func deleteBenefit(c *gin.Context, repo *BenefitRepository) {
 		return
 	}
 
+	deletedBenefit, err := repo.GetBenefitByID(ctx, benefitId)
+	if err != nil && err != ErrBenefitNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	err = repo.DeleteBenefit(ctx, benefitId)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if deletedBenefit != nil {
+		recordAuditChange(c, "benefits", benefitId, deletedBenefit, nil)
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Benefit deleted successfully"})
 }
@@ -163,12 +227,15 @@ func deleteBenefit(c *gin.Context, repo *BenefitRepository) {
 // @Produce json
 // @Param id path string true "Benefit ID"
 // @Param benefit body Benefit true "Updated benefit information"
+// @Param X-Api-Key header string true "API key of a calling backend"
 // @Success 200 {object} Benefit "Benefit updated successfully"
-// @Failure 400 {object} ErrorResponse "Invalid ID or benefit format"
+// @Failure 400 {object} ErrorResponse "Invalid ID or benefit format or unknown category"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
 // @Failure 404 {object} ErrorResponse "Benefit not found"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /benefits/{id} [put]
-func updateBenefit(c *gin.Context, repo *BenefitRepository) {
+func updateBenefit(c *gin.Context, repo *BenefitRepository, categoryRepo *CategoryRepository) {
 	ctx := c.Request.Context()
 	benefitIdString := c.Param("id")
 	benefitId, err := primitive.ObjectIDFromHex(benefitIdString)
@@ -177,7 +244,8 @@ func updateBenefit(c *gin.Context, repo *BenefitRepository) {
 		return
 	}
 
-	if _, err := repo.GetBenefitByID(ctx, benefitId); err != nil {
+	existingBenefit, err := repo.GetBenefitByID(ctx, benefitId)
+	if err != nil {
 		if err == ErrBenefitNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
@@ -196,12 +264,29 @@ func updateBenefit(c *gin.Context, repo *BenefitRepository) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Benefit ID in URL does not match ID in request body"})
 		return
 	}
+	if err := updatedBenefit.ValidateSchedule(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := updatedBenefit.ValidateTranslations(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := updatedBenefit.ValidateLimits(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateBenefitCategory(ctx, categoryRepo, &updatedBenefit); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	savedBenefit, err := repo.UpdateBenefit(ctx, &updatedBenefit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	recordAuditChange(c, "benefits", benefitId, existingBenefit, savedBenefit)
 
 	c.JSON(http.StatusOK, savedBenefit)
 }
@@ -211,7 +296,10 @@ func updateBenefit(c *gin.Context, repo *BenefitRepository) {
 // @Tags wallets
 // @Accept json
 // @Produce json
+// @Param X-Api-Key header string true "API key of a calling backend"
 // @Success 200 {array} Wallet "List of wallets"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /wallets [get]
 func getAllWallets(c *gin.Context, repo *WalletRepository) {
@@ -221,21 +309,28 @@ func getAllWallets(c *gin.Context, repo *WalletRepository) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	for i := range wallets {
+		wallets[i].WithUpcomingExpirations()
+	}
 	c.JSON(http.StatusOK, wallets)
 }
 
 // @Summary Buy a benefit
-// @Description Buys a benefit for a user, deducting from their wallet
+// @Description Buys one or more units of a benefit for a user, deducting price × quantity from their wallet. An optional promo code discounts every unit. Returns the owned benefit, or an array with one owned benefit per unit bought when quantity is sent.
 // @Tags benefits
 // @Accept json
 // @Produce json
 // @Param benefit_id path string true "Benefit ID"
 // @Param user_id body string true "User ID"
-// @Success 200 {object} OwnedBenefit "Purchased benefit"
-// @Failure 400 {object} ErrorResponse "Invalid ID format or insufficient funds"
+// @Param promo_code body string false "Promo code"
+// @Param quantity body int false "Units to buy, from 1 to 100, defaults to 1"
+// @Success 200 {object} OwnedBenefit "Purchased benefit, an array of them if quantity was sent"
+// @Failure 400 {object} ErrorResponse "Invalid ID format or quantity, insufficient funds, benefit unavailable, out of stock, purchase limit reached or unusable promo code"
+// @Failure 404 {object} ErrorResponse "Benefit or wallet not found"
+// @Failure 429 {object} ErrorResponse "Too many requests, see Retry-After"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /benefits/{benefit_id}/buy [post]
-func buyBenefit(c *gin.Context, benefitRepo *BenefitRepository, walletRepo *WalletRepository) {
+func buyBenefit(c *gin.Context, benefitRepo *BenefitRepository, walletRepo *WalletRepository, promoRepo *PromoCodeRepository, outbox *OutboxRepository, live *LiveUpdates) {
 	// Start a session
 	session, err := benefitRepo.client.StartSession()
 	if err != nil {
@@ -252,78 +347,125 @@ func buyBenefit(c *gin.Context, benefitRepo *BenefitRepository, walletRepo *Wall
 	}
 
 	var req struct {
-		UserID primitive.ObjectID `json:"user_id"`
+		UserID    primitive.ObjectID `json:"user_id"`
+		PromoCode string             `json:"promo_code"`
+		Quantity  *int               `json:"quantity"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	// Clients predating quantities send none and expect a single object
+	quantity := 1
+	if req.Quantity != nil {
+		quantity = *req.Quantity
+	}
+	if quantity < 1 || quantity > maxPurchaseQuantity {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("quantity must be between 1 and %d", maxPurchaseQuantity)})
+		return
+	}
 
 	// Define the transaction
 	transaction := func(sessCtx mongo.SessionContext) (any, error) {
-		// Perform all the steps inside the transaction
+		return purchaseBenefit(sessCtx, benefitRepo, walletRepo, promoRepo, outbox, Purchase{
+			BenefitId: benefitObjectId,
+			UserId:    req.UserID,
+			Quantity:  quantity,
+			PromoCode: req.PromoCode,
+		})
+	}
 
-		// Step 1: Get Benefit by ID
-		benefit, err := benefitRepo.GetBenefitByID(sessCtx, benefitObjectId)
-		if err != nil {
-			return nil, err
-		}
+	// Execute the transaction
+	result, err := session.WithTransaction(c.Request.Context(), transaction)
+	if err != nil {
+		c.JSON(purchaseErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
 
-		// Step 2: Get Wallet by User ID
-		wallet, err := walletRepo.GetWalletByUserID(sessCtx, req.UserID)
-		if err != nil {
-			return nil, err
-		}
+	live.WalletChanged(c.Request.Context(), req.UserID)
+	live.StockChanged(c.Request.Context(), benefitObjectId)
+	ownedBenefits := result.([]OwnedBenefit)
+	if req.Quantity == nil {
+		c.JSON(http.StatusOK, ownedBenefits[0])
+		return
+	}
+	c.JSON(http.StatusOK, ownedBenefits)
+}
 
-		// Step 3: Check and update wallet balance
-		if wallet.TokenBalance < benefit.Price {
-			return nil, errors.New("insufficient funds")
-		}
-		wallet.TokenBalance -= benefit.Price
-		_, err = walletRepo.UpdateWallet(sessCtx, wallet)
-		if err != nil {
-			return nil, err
-		}
+// purchaseErrorStatus maps errors returned from a purchase transaction to an
+// HTTP status code.
+func purchaseErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrBenefitNotFound), errors.Is(err, ErrWalletNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInsufficientFunds), errors.Is(err, ErrBenefitUnavailable), errors.Is(err, ErrOutOfStock), errors.Is(err, ErrPurchaseLimitReached),
+		errors.Is(err, ErrPromoCodeInvalid), errors.Is(err, ErrPromoCodeNotApplicable), errors.Is(err, ErrPromoCodeUsedUp):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
 
-		// Step 4: Add purchased benefit
-		ownedBenefit := OwnedBenefit{
-			OwnerId:        req.UserID,
-			BenefitId:      benefitObjectId,
-			Purchased:      time.Now(),
-			Content:        "TODO",
-			ExpirationDate: benefit.ExpirationDate,
-		}
-		savedOwnedBenefit, err := benefitRepo.AddPurchasedBenefit(sessCtx, &ownedBenefit)
-		if err != nil {
-			return nil, err
-		}
+// @Summary Get remaining purchases
+// @Description Tells how many more units of a benefit a user may buy under the benefit's purchase limits
+// @Tags benefits
+// @Accept json
+// @Produce json
+// @Param id path string true "Benefit ID"
+// @Param user_id query string true "User ID"
+// @Success 200 {object} PurchaseAllowance "Remaining purchases"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 404 {object} ErrorResponse "Benefit not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /benefits/{id}/limits [get]
+func getPurchaseAllowance(c *gin.Context, repo *BenefitRepository) {
+	ctx := c.Request.Context()
+	benefitId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userId, err := primitive.ObjectIDFromHex(c.Query("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user_id"})
+		return
+	}
 
-		return savedOwnedBenefit, nil
+	benefit, err := repo.GetBenefitByID(ctx, benefitId)
+	if err != nil {
+		if err == ErrBenefitNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
-	// Execute the transaction
-	result, err := session.WithTransaction(c.Request.Context(), transaction)
+	allowance, err := repo.RemainingPurchases(ctx, benefit, userId, time.Now())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, result)
+	c.JSON(http.StatusOK, allowance)
 }
 
 // @Summary Grant tokens to a wallet
-// @Description Grants tokens to a user's wallet
+// @Description Grants tokens to a user's wallet as a new token lot that expires 12 months after the grant
 // @Tags wallets
 // @Accept json
 // @Produce json
 // @Param user_id body string true "User ID"
 // @Param amount body int true "Amount of tokens to grant"
+// @Param X-Api-Key header string true "API key of a calling backend"
 // @Success 200 {object} Wallet "Updated wallet"
-// @Failure 400 {object} ErrorResponse "Invalid request format"
+// @Failure 400 {object} ErrorResponse "Invalid request format or non-positive amount"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 429 {object} ErrorResponse "Too many requests, see Retry-After"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /wallets/grant [post]
-func grantTokens(c *gin.Context, repo *WalletRepository) {
-	ctx := c.Request.Context()
+func grantTokens(c *gin.Context, repo *WalletRepository, leaderboardRepo *LeaderboardRepository, outbox *OutboxRepository, live *LiveUpdates) {
 	var req struct {
 		UserID primitive.ObjectID `json:"user_id"`
 		Amount int                `json:"amount"`
@@ -333,21 +475,51 @@ func grantTokens(c *gin.Context, repo *WalletRepository) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.Amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidTokenAmount.Error()})
+		return
+	}
 
-	wallet, err := repo.GetWalletByUserID(ctx, req.UserID)
+	session, err := repo.collection.Database().Client().StartSession()
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	defer session.EndSession(c.Request.Context())
 
-	wallet.TokenBalance += req.Amount
-	_, err = repo.UpdateWallet(ctx, wallet)
+	var walletBefore Wallet
+	transaction := func(sessCtx mongo.SessionContext) (any, error) {
+		wallet, err := repo.GetWalletByUserID(sessCtx, req.UserID)
+		if err != nil {
+			return nil, err
+		}
+		walletBefore = *wallet
+		walletBefore.TokenLots = slices.Clone(wallet.TokenLots)
+
+		err = creditWallet(sessCtx, repo, leaderboardRepo, outbox, wallet, TokensGrantedPayload{
+			Amount: req.Amount,
+			Source: TokenSourceManual,
+		}, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		return wallet, nil
+	}
+
+	result, err := session.WithTransaction(c.Request.Context(), transaction)
 	if err != nil {
+		if errors.Is(err, ErrWalletNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, wallet)
+	walletAfter := *result.(*Wallet)
+	recordAuditChange(c, "wallets", walletAfter.Id, &walletBefore, &walletAfter)
+	live.WalletChanged(c.Request.Context(), req.UserID)
+	c.JSON(http.StatusOK, result.(*Wallet).WithUpcomingExpirations())
 }
 
 // @Summary Get wallet by user ID
@@ -375,5 +547,92 @@ func getWalletByUserID(c *gin.Context, repo *WalletRepository) {
 		return
 	}
 
-	c.JSON(http.StatusOK, wallet)
+	c.JSON(http.StatusOK, wallet.WithUpcomingExpirations())
+}
+
+// @Summary Register a wallet
+// @Description Creates a user's wallet, optionally with the referral code of the user who invited them. Referral bonuses are paid once the new user completes a first qualifying action. Only the backend that signs users up may register wallets, with an API key holding the wallets:write scope, so the user ID is one it has verified.
+// @Tags wallets
+// @Accept json
+// @Produce json
+// @Param X-Api-Key header string true "API key with the wallets:write scope"
+// @Param user_id body string true "User ID"
+// @Param referral_code body string false "Referral code of the inviting user"
+// @Success 201 {object} Wallet "Wallet created"
+// @Failure 400 {object} ErrorResponse "Invalid request format, unknown referral code or self-referral"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 409 {object} ErrorResponse "Wallet already exists"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /wallets [post]
+func registerWallet(c *gin.Context, repo *WalletRepository, referralRepo *ReferralRepository) {
+	ctx := c.Request.Context()
+	var req struct {
+		UserID       primitive.ObjectID `json:"user_id"`
+		ReferralCode string             `json:"referral_code"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.UserID.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	session, err := repo.collection.Database().Client().StartSession()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start session"})
+		return
+	}
+	defer session.EndSession(ctx)
+
+	transaction := func(sessCtx mongo.SessionContext) (any, error) {
+		var referralCode *ReferralCode
+		if req.ReferralCode != "" {
+			code, err := referralRepo.GetCode(sessCtx, req.ReferralCode)
+			if err != nil {
+				return nil, err
+			}
+			if code.UserId == req.UserID {
+				return nil, ErrSelfReferral
+			}
+			referralCode = code
+		}
+
+		now := time.Now()
+		wallet, err := repo.AddWallet(sessCtx, &Wallet{UserId: req.UserID, TokenLots: []TokenLot{}})
+		if err != nil {
+			return nil, err
+		}
+
+		if referralCode != nil {
+			_, err := referralRepo.AddReferral(sessCtx, &Referral{
+				ReferrerId: referralCode.UserId,
+				RefereeId:  req.UserID,
+				Code:       referralCode.Code,
+				Status:     ReferralPending,
+				CreatedAt:  now,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		return wallet, nil
+	}
+
+	result, err := session.WithTransaction(ctx, transaction)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrReferralCodeNotFound), errors.Is(err, ErrSelfReferral):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrWalletExists):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, result.(*Wallet).WithUpcomingExpirations())
 }
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func benefitImageKey(benefitId primitive.ObjectID, size string) string {
+	return "benefits/" + benefitId.Hex() + "/" + size
+}
+
+// @Summary Upload a benefit image
+// @Description Uploads a JPEG or PNG image for a benefit as multipart form field "image", generates thumbnails and points the benefit's imageUrl at the stored image
+// @Tags benefits
+// @Accept multipart/form-data
+// @Produce json
+// @Param benefit_id path string true "Benefit ID"
+// @Param image formData file true "Image file, at most 5 MB"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 200 {object} Benefit "Benefit with updated image URLs"
+// @Failure 400 {object} ErrorResponse "Invalid ID or unsupported image"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 404 {object} ErrorResponse "Benefit not found"
+// @Failure 413 {object} ErrorResponse "Image too large"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /benefits/{benefit_id}/image [post]
+func uploadBenefitImage(c *gin.Context, repo *BenefitRepository, storage ImageStorage) {
+	ctx := c.Request.Context()
+	benefitId, err := primitive.ObjectIDFromHex(c.Param("benefit_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	existingBenefit, err := repo.GetBenefitByID(ctx, benefitId)
+	if err != nil {
+		if err == ErrBenefitNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Leave some room for the multipart framing around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImageBytes+1<<20)
+	fileHeader, err := c.FormFile("image")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "image must be at most 5 MB"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if fileHeader.Size > maxImageBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "image must be at most 5 MB"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxImageBytes))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	img, contentType, err := decodeUpload(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The version parameter changes with every upload so the URLs can be
+	// cached forever
+	version := strconv.FormatInt(time.Now().Unix(), 10)
+	baseUrl := "/benefits/" + benefitId.Hex() + "/image"
+	thumbnails := map[string]string{}
+	for _, width := range thumbnailWidths {
+		thumbnail, err := encodeImage(resizeImage(img, width), contentType)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		size := strconv.Itoa(width)
+		if err := storage.Save(ctx, benefitImageKey(benefitId, size), contentType, thumbnail); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		thumbnails[size] = fmt.Sprintf("%s?size=%s&v=%s", baseUrl, size, version)
+	}
+	if err := storage.Save(ctx, benefitImageKey(benefitId, "original"), contentType, data); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	benefit, err := repo.SetBenefitImage(ctx, benefitId, baseUrl+"?v="+version, thumbnails)
+	if err != nil {
+		if err == ErrBenefitNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAuditChange(c, "benefits", benefitId, existingBenefit, benefit)
+
+	c.JSON(http.StatusOK, benefit)
+}
+
+// @Summary Get a benefit image
+// @Description Serves the uploaded image of a benefit or one of its thumbnails. Versioned URLs are cacheable forever.
+// @Tags benefits
+// @Produce image/jpeg,image/png
+// @Param id path string true "Benefit ID"
+// @Param size query int false "Thumbnail width, original image if omitted"
+// @Param v query string false "Image version from the benefit's imageUrl"
+// @Success 200 {file} file "Image"
+// @Success 304 "Not modified"
+// @Failure 400 {object} ErrorResponse "Invalid ID or size"
+// @Failure 404 {object} ErrorResponse "Image not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /benefits/{id}/image [get]
+func getBenefitImage(c *gin.Context, storage ImageStorage) {
+	ctx := c.Request.Context()
+	benefitId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	size := "original"
+	if sizeParam := c.Query("size"); sizeParam != "" {
+		width, err := strconv.Atoi(sizeParam)
+		if err != nil || !slices.Contains(thumbnailWidths, width) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid size"})
+			return
+		}
+		size = sizeParam
+	}
+
+	stored, err := storage.Open(ctx, benefitImageKey(benefitId, size))
+	if err != nil {
+		if err == ErrImageNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer stored.Content.Close()
+
+	etag := fmt.Sprintf(`"%x-%x"`, stored.ModTime.UnixNano(), stored.Size)
+	if c.Query("v") != "" {
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		c.Header("Cache-Control", "public, max-age=3600")
+	}
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", stored.ModTime.UTC().Format(http.TimeFormat))
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.DataFromReader(http.StatusOK, stored.Size, stored.ContentType, stored.Content, nil)
+}
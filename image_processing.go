@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const (
+	maxImageBytes     = 5 << 20
+	maxImageDimension = 8000
+	maxImagePixels    = 25_000_000
+)
+
+// thumbnailWidths are the widths of the thumbnails generated for every
+// uploaded benefit image.
+var thumbnailWidths = []int{160, 480}
+
+var allowedImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+}
+
+var ErrUnsupportedImage = errors.New("image must be a JPEG or PNG file")
+
+// decodeUpload checks the type and dimensions of an uploaded image and
+// decodes it.
+func decodeUpload(data []byte) (image.Image, string, error) {
+	contentType := http.DetectContentType(data)
+	if !allowedImageTypes[contentType] {
+		return nil, "", ErrUnsupportedImage
+	}
+
+	// Look at the header first so huge images are rejected before decoding
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupportedImage
+	}
+	if config.Width > maxImageDimension || config.Height > maxImageDimension {
+		return nil, "", fmt.Errorf("image must be at most %dx%d pixels", maxImageDimension, maxImageDimension)
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, "", fmt.Errorf("image must have at most %d megapixels", maxImagePixels/1_000_000)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupportedImage
+	}
+	return img, contentType, nil
+}
+
+// resizeImage scales src down to the given width keeping its aspect ratio.
+// Every target pixel is the average of the source pixels it covers.
+// Images that are already narrow enough are returned unchanged.
+func resizeImage(src image.Image, width int) image.Image {
+	bounds := src.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+	if srcWidth <= width {
+		return src
+	}
+	height := max(srcHeight*width/srcWidth, 1)
+
+	dst := image.NewRGBA64(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*srcHeight/height
+		y1 := max(bounds.Min.Y+(y+1)*srcHeight/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*srcWidth/width
+			x1 := max(bounds.Min.X+(x+1)*srcWidth/width, x0+1)
+
+			var r, g, b, a, count uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r += uint64(pr)
+					g += uint64(pg)
+					b += uint64(pb)
+					a += uint64(pa)
+					count++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(r / count),
+				G: uint16(g / count),
+				B: uint16(b / count),
+				A: uint16(a / count),
+			})
+		}
+	}
+	return dst
+}
+
+// encodeImage encodes img in the given format.
+func encodeImage(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch contentType {
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+	case "image/png":
+		err = png.Encode(&buf, img)
+	default:
+		err = ErrUnsupportedImage
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
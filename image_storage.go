@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrImageNotFound = errors.New("image not found")
+
+// StoredImage is an image read back from an ImageStorage. The caller must
+// close Content.
+type StoredImage struct {
+	Content     io.ReadCloser
+	ContentType string
+	Size        int64
+	ModTime     time.Time
+}
+
+// ImageStorage keeps uploaded images under slash separated keys.
+type ImageStorage interface {
+	Save(ctx context.Context, key string, contentType string, data []byte) error
+	Open(ctx context.Context, key string) (*StoredImage, error)
+}
+
+// LocalImageStorage stores images as files below a directory.
+type LocalImageStorage struct {
+	dir string
+}
+
+func NewLocalImageStorage(dir string) (*LocalImageStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalImageStorage{dir: dir}, nil
+}
+
+func (s *LocalImageStorage) path(key string) (string, error) {
+	cleaned := filepath.Clean(filepath.FromSlash(key))
+	if filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", errors.New("invalid image key")
+	}
+	return filepath.Join(s.dir, cleaned), nil
+}
+
+func (s *LocalImageStorage) Save(ctx context.Context, key string, contentType string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see a partial image
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalImageStorage) Open(ctx context.Context, key string) (*StoredImage, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrImageNotFound
+		}
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	// The content type is not stored next to the file, so sniff it
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		file.Close()
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &StoredImage{
+		Content:     file,
+		ContentType: http.DetectContentType(head[:n]),
+		Size:        info.Size(),
+		ModTime:     info.ModTime(),
+	}, nil
+}
+
+// GridFSImageStorage stores images in a GridFS bucket using the key as the
+// file name. Saving a key again replaces the previous file.
+type GridFSImageStorage struct {
+	bucket *gridfs.Bucket
+}
+
+type gridFSImageMetadata struct {
+	ContentType string `bson:"contentType"`
+}
+
+func NewGridFSImageStorage(db *mongo.Database) (*GridFSImageStorage, error) {
+	bucket, err := gridfs.NewBucket(db, options.GridFSBucket().SetName("images"))
+	if err != nil {
+		return nil, err
+	}
+	return &GridFSImageStorage{bucket: bucket}, nil
+}
+
+func (s *GridFSImageStorage) Save(ctx context.Context, key string, contentType string, data []byte) error {
+	// Find the old revisions before uploading so the new one survives the cleanup
+	cursor, err := s.bucket.FindContext(ctx, bson.M{"filename": key})
+	if err != nil {
+		return err
+	}
+	var oldFiles []struct {
+		Id interface{} `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &oldFiles); err != nil {
+		return err
+	}
+
+	// GridFS uploads take no context, so the upload stream gets its deadline
+	// and a cancelled request aborts the upload before the file is created
+	uploadOptions := options.GridFSUpload().SetMetadata(gridFSImageMetadata{ContentType: contentType})
+	upload, err := s.bucket.OpenUploadStream(key, uploadOptions)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := upload.SetWriteDeadline(deadline); err != nil {
+			return err
+		}
+	}
+	if _, err := upload.Write(data); err != nil {
+		upload.Abort()
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		upload.Abort()
+		return err
+	}
+	if err := upload.Close(); err != nil {
+		return err
+	}
+
+	for _, old := range oldFiles {
+		if err := s.bucket.DeleteContext(ctx, old.Id); err != nil && err != gridfs.ErrFileNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *GridFSImageStorage) Open(ctx context.Context, key string) (*StoredImage, error) {
+	stream, err := s.bucket.OpenDownloadStreamByName(key)
+	if err != nil {
+		if err == gridfs.ErrFileNotFound {
+			return nil, ErrImageNotFound
+		}
+		return nil, err
+	}
+
+	file := stream.GetFile()
+	var metadata gridFSImageMetadata
+	if file.Metadata != nil {
+		if err := bson.Unmarshal(file.Metadata, &metadata); err != nil {
+			stream.Close()
+			return nil, err
+		}
+	}
+
+	return &StoredImage{
+		Content:     stream,
+		ContentType: metadata.ContentType,
+		Size:        file.Length,
+		ModTime:     file.UploadDate,
+	}, nil
+}
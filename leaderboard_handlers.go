@@ -0,0 +1,168 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	defaultLeaderboardSize = 10
+	maxLeaderboardSize     = 100
+)
+
+// Leaderboard ranks users by the tokens they were granted in the current
+// period. Me is the requesting user's entry when user_id is given and the
+// user is on the leaderboard.
+type Leaderboard struct {
+	Period      string             `json:"period"`
+	PeriodStart *time.Time         `json:"periodStart,omitempty"`
+	District    string             `json:"district,omitempty"`
+	Entries     []LeaderboardEntry `json:"entries"`
+	Me          *LeaderboardEntry  `json:"me,omitempty"`
+}
+
+// @Summary Get a leaderboard
+// @Description Ranks users by tokens granted this week, this month or of all time, city-wide or in one district. Users who opted out are not listed.
+// @Tags leaderboards
+// @Accept json
+// @Produce json
+// @Param period path string true "Leaderboard period" Enums(weekly, monthly, all-time)
+// @Param district query string false "Limit the leaderboard to a district"
+// @Param limit query int false "Number of entries, at most 100" default(10)
+// @Param user_id query string false "Also return this user's rank"
+// @Success 200 {object} Leaderboard "Leaderboard"
+// @Failure 400 {object} ErrorResponse "Invalid period, limit or user ID"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /leaderboards/{period} [get]
+func getLeaderboard(c *gin.Context, repo *LeaderboardRepository) {
+	ctx := c.Request.Context()
+	period := c.Param("period")
+	if !slices.Contains(leaderboardPeriods, period) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period"})
+		return
+	}
+	limit := defaultLeaderboardSize
+	if limitParam := c.Query("limit"); limitParam != "" {
+		var err error
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit <= 0 || limit > maxLeaderboardSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+	}
+	district := c.Query("district")
+
+	board := Leaderboard{Period: period, District: district}
+	periodStart := leaderboardPeriodStart(period, time.Now())
+	if !periodStart.IsZero() {
+		board.PeriodStart = &periodStart
+	}
+
+	entries, err := repo.GetTopEntries(ctx, period, periodStart, district, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	board.Entries = entries
+
+	if userIdParam := c.Query("user_id"); userIdParam != "" {
+		userId, err := primitive.ObjectIDFromHex(userIdParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		board.Me, err = repo.GetRankedEntry(ctx, period, periodStart, district, userId)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, board)
+}
+
+// @Summary Get a user's leaderboard settings
+// @Description Retrieves the user's district, public name and leaderboard opt-out
+// @Tags leaderboards
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} LeaderboardSettings "Leaderboard settings"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /wallets/{id}/leaderboard-settings [get]
+func getLeaderboardSettings(c *gin.Context, repo *LeaderboardRepository) {
+	userId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, err := repo.GetSettings(c.Request.Context(), userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// @Summary Update a user's leaderboard settings
+// @Description Sets the user's district, public name and leaderboard opt-out. The change applies to all leaderboards, including past tokens.
+// @Tags leaderboards
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param settings body LeaderboardSettings true "Leaderboard settings"
+// @Success 200 {object} LeaderboardSettings "Leaderboard settings updated successfully"
+// @Failure 400 {object} ErrorResponse "Invalid ID or settings"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /wallets/{id}/leaderboard-settings [put]
+func updateLeaderboardSettings(c *gin.Context, repo *LeaderboardRepository) {
+	ctx := c.Request.Context()
+	userId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var settings LeaderboardSettings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := settings.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	settings.UserId = userId
+	settings.UpdatedAt = time.Now()
+
+	existingSettings, err := repo.GetSettings(ctx, userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := repo.entryCollection.Database().Client().StartSession()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start session"})
+		return
+	}
+	defer session.EndSession(ctx)
+
+	result, err := session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (any, error) {
+		return repo.SaveSettings(sessCtx, &settings)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAuditChange(c, "leaderboard-settings", userId, existingSettings, result)
+	c.JSON(http.StatusOK, result)
+}
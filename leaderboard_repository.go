@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrInvalidLeaderboardSettings = errors.New("invalid leaderboard settings")
+
+const (
+	LeaderboardWeekly  = "weekly"
+	LeaderboardMonthly = "monthly"
+	LeaderboardAllTime = "all-time"
+)
+
+var leaderboardPeriods = []string{LeaderboardWeekly, LeaderboardMonthly, LeaderboardAllTime}
+
+// leaderboardPeriodStart returns the start of the leaderboard period
+// containing at: Monday for weekly, the first of the month for monthly and
+// the zero time for all-time.
+func leaderboardPeriodStart(period string, at time.Time) time.Time {
+	switch period {
+	case LeaderboardWeekly:
+		_, weekStart := capWindowStarts(at)
+		return weekStart
+	case LeaderboardMonthly:
+		local := at.In(cityLocation)
+		return time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, cityLocation)
+	}
+	return time.Time{}
+}
+
+// LeaderboardSettings is a user's choice of district and public name on
+// leaderboards. Users who opt out are left out of every leaderboard but
+// their tokens are still counted should they opt back in.
+type LeaderboardSettings struct {
+	UserId      primitive.ObjectID `json:"userId" bson:"_id"`
+	District    string             `json:"district" bson:"district" example:"old-town"`
+	DisplayName string             `json:"displayName" bson:"displayName"`
+	OptOut      bool               `json:"optOut" bson:"optOut"`
+	UpdatedAt   time.Time          `json:"updatedAt" bson:"updatedAt"`
+}
+
+func (s *LeaderboardSettings) Validate() error {
+	if s.District != "" && !slugPattern.MatchString(s.District) {
+		return fmt.Errorf("%w: district must be lowercase letters, digits and dashes", ErrInvalidLeaderboardSettings)
+	}
+	if len(s.DisplayName) > 50 {
+		return fmt.Errorf("%w: displayName must be at most 50 characters", ErrInvalidLeaderboardSettings)
+	}
+	return nil
+}
+
+// LeaderboardEntry holds the tokens a user was granted in one period. The
+// user's settings are copied onto the entry so leaderboards are read
+// without joins. Leaderboards are public, so users are only shown by their
+// display name.
+type LeaderboardEntry struct {
+	Id          primitive.ObjectID `json:"-" bson:"_id,omitempty"`
+	Period      string             `json:"-" bson:"period"`
+	PeriodStart time.Time          `json:"-" bson:"periodStart"`
+	Rank        int                `json:"rank" bson:"-"`
+	UserId      primitive.ObjectID `json:"-" bson:"userId"`
+	DisplayName string             `json:"displayName" bson:"displayName"`
+	District    string             `json:"district" bson:"district"`
+	Tokens      int                `json:"tokens" bson:"tokens"`
+	Hidden      bool               `json:"-" bson:"hidden"`
+	UpdatedAt   time.Time          `json:"updatedAt" bson:"updatedAt"`
+}
+
+type LeaderboardRepository struct {
+	entryCollection    *mongo.Collection
+	settingsCollection *mongo.Collection
+}
+
+func NewLeaderboardRepository(entryCollection *mongo.Collection, settingsCollection *mongo.Collection) (*LeaderboardRepository, error) {
+	_, err := entryCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "period", Value: 1}, {Key: "periodStart", Value: 1}, {Key: "userId", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "period", Value: 1}, {Key: "periodStart", Value: 1}, {Key: "hidden", Value: 1}, {Key: "tokens", Value: -1}}},
+		{Keys: bson.D{{Key: "period", Value: 1}, {Key: "periodStart", Value: 1}, {Key: "hidden", Value: 1}, {Key: "district", Value: 1}, {Key: "tokens", Value: -1}}},
+		{Keys: bson.D{{Key: "userId", Value: 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &LeaderboardRepository{
+		entryCollection:    entryCollection,
+		settingsCollection: settingsCollection,
+	}, nil
+}
+
+// GetSettings returns the user's leaderboard settings, or the defaults if
+// the user has not chosen any.
+func (r *LeaderboardRepository) GetSettings(ctx context.Context, userId primitive.ObjectID) (*LeaderboardSettings, error) {
+	var settings LeaderboardSettings
+	err := r.settingsCollection.FindOne(ctx, bson.M{"_id": userId}).Decode(&settings)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return &LeaderboardSettings{UserId: userId}, nil
+		}
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+// SaveSettings stores the user's settings and applies them to the user's
+// entries on every leaderboard.
+func (r *LeaderboardRepository) SaveSettings(ctx context.Context, settings *LeaderboardSettings) (*LeaderboardSettings, error) {
+	opts := options.Replace().SetUpsert(true)
+	if _, err := r.settingsCollection.ReplaceOne(ctx, bson.M{"_id": settings.UserId}, settings, opts); err != nil {
+		return nil, err
+	}
+	update := bson.M{"$set": bson.M{
+		"district":    settings.District,
+		"displayName": settings.DisplayName,
+		"hidden":      settings.OptOut,
+	}}
+	if _, err := r.entryCollection.UpdateMany(ctx, bson.M{"userId": settings.UserId}, update); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// AddGrant counts tokens granted to the user at the given time on the
+// weekly, monthly and all-time leaderboards.
+func (r *LeaderboardRepository) AddGrant(ctx context.Context, userId primitive.ObjectID, amount int, at time.Time) error {
+	settings, err := r.GetSettings(ctx, userId)
+	if err != nil {
+		return err
+	}
+	opts := options.Update().SetUpsert(true)
+	for _, period := range leaderboardPeriods {
+		filter := bson.M{"period": period, "periodStart": leaderboardPeriodStart(period, at), "userId": userId}
+		update := bson.M{
+			"$inc": bson.M{"tokens": amount},
+			"$set": bson.M{
+				"district":    settings.District,
+				"displayName": settings.DisplayName,
+				"hidden":      settings.OptOut,
+				"updatedAt":   at,
+			},
+		}
+		if _, err := r.entryCollection.UpdateOne(ctx, filter, update, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FirstTrackedWeeks returns, per user, the start of the earliest week in
+// which a grant was counted on the leaderboards.
+func (r *LeaderboardRepository) FirstTrackedWeeks(ctx context.Context) (map[primitive.ObjectID]time.Time, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"period": LeaderboardWeekly}}},
+		{{Key: "$group", Value: bson.M{"_id": "$userId", "firstWeek": bson.M{"$min": "$periodStart"}}}},
+	}
+	cursor, err := r.entryCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	firstWeeks := map[primitive.ObjectID]time.Time{}
+	for cursor.Next(ctx) {
+		var result struct {
+			UserId    primitive.ObjectID `bson:"_id"`
+			FirstWeek time.Time          `bson:"firstWeek"`
+		}
+		if err := cursor.Decode(&result); err != nil {
+			return nil, err
+		}
+		firstWeeks[result.UserId] = result.FirstWeek
+	}
+	return firstWeeks, cursor.Err()
+}
+
+// AddUntrackedTokens counts tokens granted before the leaderboards existed
+// on the user's all-time entry only.
+func (r *LeaderboardRepository) AddUntrackedTokens(ctx context.Context, userId primitive.ObjectID, amount int, lastGrantedAt time.Time) error {
+	settings, err := r.GetSettings(ctx, userId)
+	if err != nil {
+		return err
+	}
+	filter := bson.M{"period": LeaderboardAllTime, "periodStart": time.Time{}, "userId": userId}
+	update := bson.M{
+		"$inc": bson.M{"tokens": amount},
+		"$set": bson.M{
+			"district":    settings.District,
+			"displayName": settings.DisplayName,
+			"hidden":      settings.OptOut,
+		},
+		"$setOnInsert": bson.M{"updatedAt": lastGrantedAt},
+	}
+	_, err = r.entryCollection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// boardFilter matches the visible entries of a leaderboard, optionally
+// limited to a district.
+func boardFilter(period string, periodStart time.Time, district string) bson.M {
+	filter := bson.M{"period": period, "periodStart": periodStart, "hidden": false}
+	if district != "" {
+		filter["district"] = district
+	}
+	return filter
+}
+
+// GetTopEntries returns the visible entries with the most tokens, ranked.
+// Users with the same tokens share a rank and the one who got there first
+// is listed first.
+func (r *LeaderboardRepository) GetTopEntries(ctx context.Context, period string, periodStart time.Time, district string, limit int) ([]LeaderboardEntry, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "tokens", Value: -1}, {Key: "updatedAt", Value: 1}}).
+		SetLimit(int64(limit))
+	cursor, err := r.entryCollection.Find(ctx, boardFilter(period, periodStart, district), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []LeaderboardEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if i > 0 && entries[i].Tokens == entries[i-1].Tokens {
+			entries[i].Rank = entries[i-1].Rank
+		} else {
+			entries[i].Rank = i + 1
+		}
+	}
+	return entries, nil
+}
+
+// GetRankedEntry returns the user's visible entry on a leaderboard with its
+// rank, or nil if the user has none or opted out.
+func (r *LeaderboardRepository) GetRankedEntry(ctx context.Context, period string, periodStart time.Time, district string, userId primitive.ObjectID) (*LeaderboardEntry, error) {
+	filter := boardFilter(period, periodStart, district)
+	filter["userId"] = userId
+	var entry LeaderboardEntry
+	if err := r.entryCollection.FindOne(ctx, filter).Decode(&entry); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	ahead := boardFilter(period, periodStart, district)
+	ahead["tokens"] = bson.M{"$gt": entry.Tokens}
+	count, err := r.entryCollection.CountDocuments(ctx, ahead)
+	if err != nil {
+		return nil, err
+	}
+	entry.Rank = int(count) + 1
+	return &entry, nil
+}
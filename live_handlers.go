@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	maxWatchedBenefits  = 50
+	liveHeartbeatPeriod = 25 * time.Second
+)
+
+// @Summary Stream wallet and stock updates
+// @Description Opens a Server-Sent Events stream. It starts with the current wallet balance and the stock of every watched benefit, then sends a "wallet" event whenever the user's balance changes and a "stock" event whenever a watched benefit's stock changes. A comment line is sent every 25 seconds to keep the connection open.
+// @Tags wallets
+// @Produce text/event-stream
+// @Param id path string true "User ID"
+// @Param benefits query string false "Comma separated IDs of benefits to watch, at most 50"
+// @Success 200 {object} LiveUpdate "Stream of wallet (WalletBalance) and stock (StockLevel) events"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 404 {object} ErrorResponse "Wallet not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /wallets/{id}/stream [get]
+func streamLiveUpdates(c *gin.Context, broker *LiveBroker, live *LiveUpdates) {
+	ctx := c.Request.Context()
+	userId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var benefitIds []primitive.ObjectID
+	if watched := c.Query("benefits"); watched != "" {
+		for _, hex := range strings.Split(watched, ",") {
+			benefitId, err := primitive.ObjectIDFromHex(strings.TrimSpace(hex))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid benefit ID %q", hex)})
+				return
+			}
+			benefitIds = append(benefitIds, benefitId)
+		}
+	}
+	if len(benefitIds) > maxWatchedBenefits {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d benefits can be watched", maxWatchedBenefits)})
+		return
+	}
+
+	// Subscribe before reading the snapshot so no change falls in between
+	topics := []string{walletTopic(userId)}
+	for _, benefitId := range benefitIds {
+		topics = append(topics, stockTopic(benefitId))
+	}
+	sub := broker.Subscribe(topics...)
+	defer broker.Unsubscribe(sub)
+
+	snapshot := make([]LiveUpdate, 0, len(benefitIds)+1)
+	walletUpdate, err := live.walletUpdate(ctx, userId)
+	if err != nil {
+		if err == ErrWalletNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	snapshot = append(snapshot, walletUpdate)
+	for _, benefitId := range benefitIds {
+		stockUpdate, err := live.stockUpdate(ctx, benefitId)
+		if err != nil {
+			if err == ErrBenefitNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		snapshot = append(snapshot, stockUpdate)
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	for _, update := range snapshot {
+		c.SSEvent(update.Type, update.Data)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(liveHeartbeatPeriod)
+	defer heartbeat.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case update := <-sub.C:
+			c.SSEvent(update.Type, update.Data)
+		case <-heartbeat.C:
+			io.WriteString(w, ": keep-alive\n\n")
+		}
+		return true
+	})
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	LiveWallet = "wallet"
+	LiveStock  = "stock"
+
+	// Updates for a subscriber whose buffer is full are dropped. Every
+	// update carries the full current value, so the next one catches up.
+	liveBufferSize = 16
+)
+
+// LiveUpdate is a change pushed to connected clients.
+type LiveUpdate struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+// WalletBalance uses the field names of Wallet.
+type WalletBalance struct {
+	UserId       primitive.ObjectID `json:"user_id"`
+	MoneyBalance float64            `json:"money_balance"`
+	TokenBalance int                `json:"token_balance"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+}
+
+type StockLevel struct {
+	BenefitId primitive.ObjectID `json:"benefitId"`
+	InStock   int                `json:"inStock"`
+	UpdatedAt time.Time          `json:"updatedAt"`
+}
+
+func walletTopic(userId primitive.ObjectID) string {
+	return "wallet:" + userId.Hex()
+}
+
+func stockTopic(benefitId primitive.ObjectID) string {
+	return "stock:" + benefitId.Hex()
+}
+
+// LiveSubscription receives the updates of the topics it subscribed to.
+type LiveSubscription struct {
+	C      chan LiveUpdate
+	topics []string
+}
+
+// LiveBroker is an in-process publish/subscribe hub. It only reaches
+// clients connected to this replica.
+type LiveBroker struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[*LiveSubscription]struct{}
+}
+
+func NewLiveBroker() *LiveBroker {
+	return &LiveBroker{subscribers: map[string]map[*LiveSubscription]struct{}{}}
+}
+
+func (b *LiveBroker) Subscribe(topics ...string) *LiveSubscription {
+	sub := &LiveSubscription{C: make(chan LiveUpdate, liveBufferSize), topics: topics}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, topic := range topics {
+		if b.subscribers[topic] == nil {
+			b.subscribers[topic] = map[*LiveSubscription]struct{}{}
+		}
+		b.subscribers[topic][sub] = struct{}{}
+	}
+	return sub
+}
+
+func (b *LiveBroker) Unsubscribe(sub *LiveSubscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, topic := range sub.topics {
+		delete(b.subscribers[topic], sub)
+		if len(b.subscribers[topic]) == 0 {
+			delete(b.subscribers, topic)
+		}
+	}
+}
+
+// Publish hands the update to every subscriber of the topic without
+// waiting for slow ones.
+func (b *LiveBroker) Publish(topic string, update LiveUpdate) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subscribers[topic] {
+		select {
+		case sub.C <- update:
+		default:
+		}
+	}
+}
+
+func (b *LiveBroker) hasSubscribers(topic string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subscribers[topic]) > 0
+}
+
+// LiveUpdates reads the current wallet balance or stock level after a
+// committed change and publishes it. Reading instead of passing values
+// along keeps concurrent changes from publishing stale numbers last.
+type LiveUpdates struct {
+	broker      *LiveBroker
+	walletRepo  *WalletRepository
+	benefitRepo *BenefitRepository
+}
+
+func NewLiveUpdates(broker *LiveBroker, walletRepo *WalletRepository, benefitRepo *BenefitRepository) *LiveUpdates {
+	return &LiveUpdates{
+		broker:      broker,
+		walletRepo:  walletRepo,
+		benefitRepo: benefitRepo,
+	}
+}
+
+// WalletChanged publishes the balance of the user's wallet.
+func (l *LiveUpdates) WalletChanged(ctx context.Context, userId primitive.ObjectID) {
+	if !l.broker.hasSubscribers(walletTopic(userId)) {
+		return
+	}
+	update, err := l.walletUpdate(ctx, userId)
+	if err != nil {
+		log.Printf("failed to publish wallet of %s: %v", userId.Hex(), err)
+		return
+	}
+	l.broker.Publish(walletTopic(userId), update)
+}
+
+// StockChanged publishes the stock levels of the benefits.
+func (l *LiveUpdates) StockChanged(ctx context.Context, benefitIds ...primitive.ObjectID) {
+	for _, benefitId := range benefitIds {
+		if !l.broker.hasSubscribers(stockTopic(benefitId)) {
+			continue
+		}
+		update, err := l.stockUpdate(ctx, benefitId)
+		if err != nil {
+			log.Printf("failed to publish stock of %s: %v", benefitId.Hex(), err)
+			continue
+		}
+		l.broker.Publish(stockTopic(benefitId), update)
+	}
+}
+
+func (l *LiveUpdates) walletUpdate(ctx context.Context, userId primitive.ObjectID) (LiveUpdate, error) {
+	wallet, err := l.walletRepo.GetWalletByUserID(ctx, userId)
+	if err != nil {
+		return LiveUpdate{}, err
+	}
+	return LiveUpdate{Type: LiveWallet, Data: WalletBalance{
+		UserId:       wallet.UserId,
+		TokenBalance: wallet.TokenBalance,
+		MoneyBalance: wallet.MoneyBalance,
+		UpdatedAt:    time.Now(),
+	}}, nil
+}
+
+func (l *LiveUpdates) stockUpdate(ctx context.Context, benefitId primitive.ObjectID) (LiveUpdate, error) {
+	benefit, err := l.benefitRepo.GetBenefitByID(ctx, benefitId)
+	if err != nil {
+		return LiveUpdate{}, err
+	}
+	return LiveUpdate{Type: LiveStock, Data: StockLevel{
+		BenefitId: benefit.Id,
+		InStock:   benefit.InStock,
+		UpdatedAt: time.Now(),
+	}}, nil
+}
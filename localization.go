@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// defaultLocale is the language of Benefit.Name and Benefit.Description.
+const defaultLocale = "pl"
+
+var supportedLocales = []string{"pl", "en"}
+
+// BenefitTranslation holds the texts of a benefit in one language.
+type BenefitTranslation struct {
+	Name        string `json:"name" bson:"name"`
+	Description string `json:"description" bson:"description"`
+}
+
+// ValidateTranslations checks that translations only use supported locales
+// and carry a name.
+func (b *Benefit) ValidateTranslations() error {
+	for locale, translation := range b.Translations {
+		if !slices.Contains(supportedLocales, locale) {
+			return fmt.Errorf("unsupported locale %q", locale)
+		}
+		if translation.Name == "" {
+			return fmt.Errorf("translation %q has no name", locale)
+		}
+	}
+	return nil
+}
+
+// Localize fills LocalizedName and LocalizedDescription with the texts in
+// the given locale, falling back to the default locale texts when there is
+// no translation. Name and Description keep the default locale texts so a
+// localized benefit can be sent back in an update unchanged.
+func (b *Benefit) Localize(locale string) {
+	b.Locale = defaultLocale
+	b.LocalizedName = b.Name
+	b.LocalizedDescription = b.Description
+	if locale == defaultLocale {
+		return
+	}
+	translation, ok := b.Translations[locale]
+	if !ok {
+		return
+	}
+	b.LocalizedName = translation.Name
+	if translation.Description != "" {
+		b.LocalizedDescription = translation.Description
+	}
+	b.Locale = locale
+}
+
+// requestLocale picks the locale from the lang query param or the
+// Accept-Language header, defaulting to defaultLocale.
+func requestLocale(c *gin.Context) string {
+	if lang := normalizeLocale(c.Query("lang")); slices.Contains(supportedLocales, lang) {
+		return lang
+	}
+	for _, lang := range parseAcceptLanguage(c.GetHeader("Accept-Language")) {
+		if slices.Contains(supportedLocales, lang) {
+			return lang
+		}
+	}
+	return defaultLocale
+}
+
+// parseAcceptLanguage returns the languages of an Accept-Language header
+// ordered by preference.
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		lang    string
+		quality float64
+	}
+	var langs []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality > 0 {
+			langs = append(langs, weighted{normalizeLocale(tag), quality})
+		}
+	}
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].quality > langs[j].quality })
+
+	result := make([]string, 0, len(langs))
+	for _, l := range langs {
+		result = append(result, l.lang)
+	}
+	return result
+}
+
+// normalizeLocale reduces a language tag such as "en-GB" to "en".
+func normalizeLocale(tag string) string {
+	lang, _, _ := strings.Cut(strings.TrimSpace(tag), "-")
+	return strings.ToLower(lang)
+}
+
+// localizedSearchFilter matches the search term against the name in the
+// given locale, using the default name for benefits without a translation.
+func localizedSearchFilter(search, locale string) bson.M {
+	pattern := bson.M{"$regex": search, "$options": "i"}
+	if locale == defaultLocale {
+		return bson.M{"name": pattern}
+	}
+	translatedName := "translations." + locale + ".name"
+	return bson.M{"$or": bson.A{
+		bson.M{translatedName: pattern},
+		bson.M{translatedName: bson.M{"$exists": false}, "name": pattern},
+	}}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrLeaseLost = errors.New("lease is held by another owner")
+
+// MongoLock is a lease stored as a document in the locks collection. It is
+// used to elect a single replica to run a background job.
+type MongoLock struct {
+	collection *mongo.Collection
+	owner      string
+}
+
+type lockDocument struct {
+	Name      string    `bson:"_id"`
+	Owner     string    `bson:"owner"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+func NewMongoLock(collection *mongo.Collection, owner string) *MongoLock {
+	return &MongoLock{
+		collection: collection,
+		owner:      owner,
+	}
+}
+
+// TryAcquire takes or renews the named lease for ttl. It returns false if
+// another owner holds a lease that has not expired yet.
+func (l *MongoLock) TryAcquire(ctx context.Context, name string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	filter := bson.M{
+		"_id": name,
+		"$or": bson.A{
+			bson.M{"owner": l.owner},
+			bson.M{"expiresAt": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{"$set": lockDocument{
+		Name:      name,
+		Owner:     l.owner,
+		ExpiresAt: now.Add(ttl),
+	}}
+	_, err := l.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		// The upsert collides with the existing document when someone else holds the lease
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Renew extends the named lease held by this owner for ttl. It fails with
+// ErrLeaseLost if another owner took the lease over in the meantime.
+func (l *MongoLock) Renew(ctx context.Context, name string, ttl time.Duration) error {
+	filter := bson.M{"_id": name, "owner": l.owner}
+	update := bson.M{"$set": bson.M{"expiresAt": time.Now().Add(ttl)}}
+	result, err := l.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// Release gives up the named lease if it is held by this owner.
+func (l *MongoLock) Release(ctx context.Context, name string) error {
+	_, err := l.collection.DeleteOne(ctx, bson.M{"_id": name, "owner": l.owner})
+	return err
+}
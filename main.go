@@ -3,6 +3,12 @@ package main
 import (
 	"context"
 	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata"
 
 	_ "payments-service/docs"
 
@@ -10,6 +16,7 @@ import (
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -31,51 +38,484 @@ func main() {
 		log.Fatal(err)
 	}
 
-	benefitRepo, err := NewBenefitRepository(client, client.Database(dbName).Collection("benefits"), client.Database(dbName).Collection("owned_benefits"))
+	benefitRepo, err := NewBenefitRepository(client, client.Database(dbName).Collection("benefits"), client.Database(dbName).Collection("owned_benefits"), client.Database(dbName).Collection("purchase_counters"))
 	if err != nil {
 		log.Fatal(err)
 	}
-	walletRepo, err := NewWalletRepository(client.Database(dbName).Collection("wallets"))
+	walletRepo, err := NewWalletRepository(client.Database(dbName).Collection("wallets"), client.Database(dbName).Collection("token_expirations"))
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	promoRepo, err := NewPromoCodeRepository(client.Database(dbName).Collection("promo_codes"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bundleRepo, err := NewBundleRepository(client.Database(dbName).Collection("bundles"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cartRepo, err := NewCartRepository(client.Database(dbName).Collection("carts"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	reservationRepo, err := NewReservationRepository(client.Database(dbName).Collection("reservations"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	partnerRepo, err := NewPartnerRepository(client.Database(dbName).Collection("partners"), client.Database(dbName).Collection("partner_credentials"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	settlementRepo, err := NewSettlementRepository(client.Database(dbName).Collection("settlements"), client.Database(dbName).Collection("owned_benefits"), client.Database(dbName).Collection("settlement_locks"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	categoryRepo, err := NewCategoryRepository(client.Database(dbName).Collection("categories"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	outboxRepo, err := NewOutboxRepository(client.Database(dbName).Collection("outbox"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	webhookRepo, err := NewWebhookRepository(client.Database(dbName).Collection("webhooks"), client.Database(dbName).Collection("webhook_deliveries"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	earningRepo, err := NewEarningRepository(client.Database(dbName).Collection("earning_rules"), client.Database(dbName).Collection("action_events"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	challengeRepo, err := NewChallengeRepository(client.Database(dbName).Collection("challenges"), client.Database(dbName).Collection("challenge_progress"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	leaderboardRepo, err := NewLeaderboardRepository(client.Database(dbName).Collection("leaderboard_entries"), client.Database(dbName).Collection("leaderboard_settings"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	referralRepo, err := NewReferralRepository(client.Database(dbName).Collection("referral_codes"), client.Database(dbName).Collection("referrals"), client.Database(dbName).Collection("settings"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	auditRepo, err := NewAuditRepository(client.Database(dbName).Collection("audit_log"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	apiKeyRepo, err := NewApiKeyRepository(client.Database(dbName).Collection("api_keys"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	migrationRepo, err := NewMigrationRepository(client.Database(dbName).Collection("migrations"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Uploaded images live on the local disk unless GridFS is requested
+	var imageStorage ImageStorage
+	if getEnv("IMAGE_STORAGE", "local") == "gridfs" {
+		imageStorage, err = NewGridFSImageStorage(client.Database(dbName))
+	} else {
+		imageStorage, err = NewLocalImageStorage(getEnv("IMAGE_DIR", "uploads"))
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Rate limit buckets are kept in memory unless replicas must share them
+	var rateLimitStore RateLimitStore = NewMemoryRateLimitStore()
+	if getEnv("RATE_LIMIT_STORE", "memory") == "mongo" {
+		rateLimitStore, err = NewMongoRateLimitStore(client.Database(dbName).Collection("rate_limits"))
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	purchaseLimits, err := userAndIPLimits(getEnv("RATE_LIMIT_PURCHASE_USER", "10/m"), getEnv("RATE_LIMIT_PURCHASE_IP", "60/m"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	grantLimits, err := userAndIPLimits(getEnv("RATE_LIMIT_GRANT_USER", "30/m"), getEnv("RATE_LIMIT_GRANT_IP", "120/m"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Calendar days and weeks follow the city's time zone
+	cityLocation, err = time.LoadLocation(getEnv("CITY_TIMEZONE", "Europe/Warsaw"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Money value of one token used in partner settlements
+	tokenRate, err := strconv.ParseFloat(getEnv("TOKEN_MONEY_RATE", "0.10"), 64)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Replicas elect one of them to run migrations and background jobs
+	instanceId := primitive.NewObjectID().Hex()
+	jobLock := NewMongoLock(client.Database(dbName).Collection("locks"), instanceId)
+
+	// Bring data written by older versions up to date
+	migrations := []Migration{
+		{Name: "owned-benefit-voucher-codes", Run: benefitRepo.BackfillVoucherCodes},
+		{Name: "owned-benefit-partner-ids", Run: benefitRepo.BackfillOwnedBenefitPartners},
+		{Name: "benefit-category-slugs", Run: func(ctx context.Context) error {
+			return migrateFreeTextCategories(ctx, categoryRepo, benefitRepo)
+		}},
+		{Name: "benefit-external-ids", Run: benefitRepo.BackfillExternalIds},
+		{Name: "all-time-leaderboard", Run: func(ctx context.Context) error {
+			return backfillAllTimeLeaderboard(ctx, walletRepo, leaderboardRepo)
+		}},
+	}
+	if err := runMigrations(context.Background(), migrationRepo, jobLock, migrations); err != nil {
+		log.Fatal(err)
+	}
+
+	// Push wallet and stock changes to connected clients
+	broker := NewLiveBroker()
+	live := NewLiveUpdates(broker, walletRepo, benefitRepo)
+
+	// Expire token lots in the background
+	go NewTokenExpirySweeper(walletRepo, outboxRepo, live, time.Hour).Run(context.Background())
+
+	// Expire benefits and owned benefits on a single elected replica
+	go NewExpirationJob(benefitRepo, jobLock, outboxRepo, 5*time.Minute).Run(context.Background())
+
+	// Deliver outbox events to the event sinks
+	eventSinks := map[string]EventPublisher{
+		"log":      LogEventPublisher{},
+		"webhooks": NewWebhookSink(webhookRepo),
+	}
+	go NewOutboxDispatcher(outboxRepo, jobLock, eventSinks, 5*time.Second).Run(context.Background())
+
+	// Send queued webhook deliveries
+	webhookDeliverer := NewWebhookDeliverer(webhookRepo, jobLock, &http.Client{Timeout: 10 * time.Second}, 5*time.Second)
+	go webhookDeliverer.Run(context.Background())
+
+	// Release expired stock reservations
+	go NewReservationSweeper(reservationRepo, benefitRepo, live, time.Minute).Run(context.Background())
+
 	// Create a new Gin router
 	r := gin.Default()
 
+	// Only the proxies in front of the service may name the client IP in
+	// X-Forwarded-For; without any the connection's address is used
+	var trustedProxies []string
+	for _, proxy := range strings.Split(getEnv("TRUSTED_PROXIES", ""), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trustedProxies = append(trustedProxies, proxy)
+		}
+	}
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatal(err)
+	}
+
 	r.Use(cors.Default())
+	r.Use(requestID())
+	r.Use(auditLog(auditRepo))
+	r.Use(apiKeyAuth(apiKeyRepo, apiKeyRouteScopes, getEnv("ADMIN_API_KEY", "")))
 
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	// Define your routes here
 	r.GET("/benefits", func(c *gin.Context) {
-		getBenefits(c, benefitRepo)
+		getBenefits(c, benefitRepo, categoryRepo)
 	})
 	r.POST("/benefits", func(c *gin.Context) {
-		addBenefit(c, benefitRepo)
+		addBenefit(c, benefitRepo, categoryRepo)
 	})
 
+	r.GET("/categories", func(c *gin.Context) {
+		getCategories(c, categoryRepo, benefitRepo)
+	})
+	r.POST("/categories", func(c *gin.Context) {
+		addCategory(c, categoryRepo)
+	})
+	r.GET("/categories/:id", func(c *gin.Context) {
+		getCategory(c, categoryRepo)
+	})
+	r.PUT("/categories/:id", func(c *gin.Context) {
+		updateCategory(c, categoryRepo, benefitRepo)
+	})
+	r.DELETE("/categories/:id", func(c *gin.Context) {
+		deleteCategory(c, categoryRepo, benefitRepo)
+	})
+
+	r.POST("/benefits/import", func(c *gin.Context) {
+		importBenefits(c, benefitRepo, categoryRepo)
+	})
+	r.GET("/benefits/export", func(c *gin.Context) {
+		exportBenefits(c, benefitRepo)
+	})
 	r.GET("/benefits/:id", func(c *gin.Context) {
 		getBenefit(c, benefitRepo)
 	})
 	r.PUT("/benefits/:id", func(c *gin.Context) {
-		updateBenefit(c, benefitRepo)
+		updateBenefit(c, benefitRepo, categoryRepo)
 	})
 	r.DELETE("/benefits/:id", func(c *gin.Context) {
 		deleteBenefit(c, benefitRepo)
 	})
 
-	r.POST("/benefits/:benefit_id/buy", func(c *gin.Context) {
-		buyBenefit(c, benefitRepo, walletRepo)
+	r.POST("/benefits/:benefit_id/buy", rateLimit(rateLimitStore, "buy", purchaseLimits...), func(c *gin.Context) {
+		buyBenefit(c, benefitRepo, walletRepo, promoRepo, outboxRepo, live)
+	})
+	r.GET("/benefits/:id/limits", func(c *gin.Context) {
+		getPurchaseAllowance(c, benefitRepo)
+	})
+	r.POST("/benefits/:benefit_id/image", func(c *gin.Context) {
+		uploadBenefitImage(c, benefitRepo, imageStorage)
+	})
+	r.GET("/benefits/:id/image", func(c *gin.Context) {
+		getBenefitImage(c, imageStorage)
+	})
+	r.POST("/benefits/:benefit_id/reserve", rateLimit(rateLimitStore, "reserve", purchaseLimits...), func(c *gin.Context) {
+		reserveBenefit(c, reservationRepo, benefitRepo, walletRepo, live)
+	})
+	r.GET("/reservations/:id", func(c *gin.Context) {
+		getReservation(c, reservationRepo)
+	})
+	r.POST("/reservations/:id/confirm", func(c *gin.Context) {
+		confirmReservation(c, reservationRepo, benefitRepo, walletRepo, promoRepo, outboxRepo, live)
+	})
+	r.POST("/reservations/:id/cancel", func(c *gin.Context) {
+		cancelReservation(c, reservationRepo, benefitRepo, live)
+	})
+	r.GET("/bundles", func(c *gin.Context) {
+		getBundles(c, bundleRepo)
+	})
+	r.POST("/bundles", func(c *gin.Context) {
+		addBundle(c, bundleRepo, benefitRepo)
+	})
+	r.GET("/bundles/:id", func(c *gin.Context) {
+		getBundle(c, bundleRepo)
+	})
+	r.PUT("/bundles/:id", func(c *gin.Context) {
+		updateBundle(c, bundleRepo, benefitRepo)
+	})
+	r.DELETE("/bundles/:id", func(c *gin.Context) {
+		deleteBundle(c, bundleRepo)
+	})
+	r.POST("/bundles/:bundle_id/buy", rateLimit(rateLimitStore, "bundle-buy", purchaseLimits...), func(c *gin.Context) {
+		buyBundle(c, bundleRepo, benefitRepo, walletRepo, outboxRepo, live)
+	})
+
+	r.GET("/carts/:user_id", func(c *gin.Context) {
+		getCart(c, cartRepo)
+	})
+	r.POST("/carts/:user_id/items", func(c *gin.Context) {
+		addCartItem(c, cartRepo, benefitRepo)
+	})
+	r.PUT("/carts/:user_id/items/:benefit_id", func(c *gin.Context) {
+		updateCartItem(c, cartRepo, benefitRepo)
+	})
+	r.DELETE("/carts/:user_id/items/:benefit_id", func(c *gin.Context) {
+		removeCartItem(c, cartRepo)
+	})
+	r.POST("/carts/:user_id/checkout", rateLimit(rateLimitStore, "checkout", purchaseLimits...), func(c *gin.Context) {
+		checkoutCart(c, cartRepo, benefitRepo, walletRepo, outboxRepo, live)
+	})
+
+	r.GET("/partners", func(c *gin.Context) {
+		getPartners(c, partnerRepo)
+	})
+	r.POST("/partners", func(c *gin.Context) {
+		addPartner(c, partnerRepo)
+	})
+	r.GET("/partners/:id", func(c *gin.Context) {
+		getPartner(c, partnerRepo)
+	})
+	r.PUT("/partners/:id", func(c *gin.Context) {
+		updatePartner(c, partnerRepo)
+	})
+	r.DELETE("/partners/:id", func(c *gin.Context) {
+		deletePartner(c, partnerRepo)
+	})
+	r.GET("/partners/:id/credentials", func(c *gin.Context) {
+		getPartnerCredentials(c, partnerRepo)
+	})
+	r.POST("/partners/:id/credentials", func(c *gin.Context) {
+		createPartnerCredential(c, partnerRepo)
+	})
+	r.DELETE("/partners/:id/credentials/:credential_id", func(c *gin.Context) {
+		revokePartnerCredential(c, partnerRepo)
+	})
+
+	r.GET("/partners/:id/settlements", func(c *gin.Context) {
+		getSettlements(c, settlementRepo)
+	})
+	r.POST("/partners/:id/settlements", func(c *gin.Context) {
+		settlePeriod(c, settlementRepo, partnerRepo, benefitRepo, tokenRate)
+	})
+	r.GET("/partners/:id/settlements/report", func(c *gin.Context) {
+		getSettlementReport(c, settlementRepo, benefitRepo, tokenRate)
+	})
+
+	// Routes for partners authenticated with their own API key
+	partner := r.Group("/partner", partnerAuth(partnerRepo))
+	partner.GET("/benefits", func(c *gin.Context) {
+		getPartnerBenefits(c, benefitRepo)
+	})
+	partner.POST("/benefits", func(c *gin.Context) {
+		addPartnerBenefit(c, benefitRepo, categoryRepo)
+	})
+	partner.PUT("/benefits/:id", func(c *gin.Context) {
+		updatePartnerBenefit(c, benefitRepo, categoryRepo)
+	})
+	partner.DELETE("/benefits/:id", func(c *gin.Context) {
+		deletePartnerBenefit(c, benefitRepo)
+	})
+	partner.POST("/vouchers/redeem", func(c *gin.Context) {
+		redeemVoucher(c, benefitRepo, outboxRepo)
 	})
+
 	r.GET("/wallets", func(c *gin.Context) {
 		getAllWallets(c, walletRepo)
 	})
 	r.GET("/wallets/:id", func(c *gin.Context) {
 		getWalletByUserID(c, walletRepo)
 	})
-	r.POST("/tokens/grant", func(c *gin.Context) {
-		grantTokens(c, walletRepo)
+	r.GET("/wallets/:id/stream", func(c *gin.Context) {
+		streamLiveUpdates(c, broker, live)
+	})
+	r.GET("/wallets/:id/challenges", func(c *gin.Context) {
+		getUserChallenges(c, challengeRepo)
+	})
+	r.POST("/wallets", func(c *gin.Context) {
+		registerWallet(c, walletRepo, referralRepo)
+	})
+	r.GET("/wallets/:id/referral-code", func(c *gin.Context) {
+		getReferralCode(c, referralRepo, walletRepo)
+	})
+	r.GET("/wallets/:id/referrals", func(c *gin.Context) {
+		getReferrals(c, referralRepo)
+	})
+	r.GET("/referral-settings", func(c *gin.Context) {
+		getReferralSettings(c, referralRepo)
+	})
+	r.PUT("/referral-settings", func(c *gin.Context) {
+		updateReferralSettings(c, referralRepo)
+	})
+	r.GET("/wallets/:id/leaderboard-settings", func(c *gin.Context) {
+		getLeaderboardSettings(c, leaderboardRepo)
+	})
+	r.PUT("/wallets/:id/leaderboard-settings", func(c *gin.Context) {
+		updateLeaderboardSettings(c, leaderboardRepo)
+	})
+	r.GET("/leaderboards/:period", func(c *gin.Context) {
+		getLeaderboard(c, leaderboardRepo)
+	})
+	r.POST("/tokens/grant", rateLimit(rateLimitStore, "grant", grantLimits...), func(c *gin.Context) {
+		grantTokens(c, walletRepo, leaderboardRepo, outboxRepo, live)
+	})
+
+	r.GET("/earning-rules", func(c *gin.Context) {
+		getEarningRules(c, earningRepo)
+	})
+	r.POST("/earning-rules", func(c *gin.Context) {
+		addEarningRule(c, earningRepo)
+	})
+	r.GET("/earning-rules/:id", func(c *gin.Context) {
+		getEarningRule(c, earningRepo)
+	})
+	r.PUT("/earning-rules/:id", func(c *gin.Context) {
+		updateEarningRule(c, earningRepo)
+	})
+	r.DELETE("/earning-rules/:id", func(c *gin.Context) {
+		deleteEarningRule(c, earningRepo)
+	})
+	r.POST("/actions", func(c *gin.Context) {
+		reportAction(c, earningRepo, challengeRepo, benefitRepo, walletRepo, leaderboardRepo, referralRepo, outboxRepo, live)
+	})
+
+	r.GET("/challenges", func(c *gin.Context) {
+		getChallenges(c, challengeRepo)
+	})
+	r.POST("/challenges", func(c *gin.Context) {
+		addChallenge(c, challengeRepo, benefitRepo)
+	})
+	r.GET("/challenges/:id", func(c *gin.Context) {
+		getChallenge(c, challengeRepo)
+	})
+	r.PUT("/challenges/:id", func(c *gin.Context) {
+		updateChallenge(c, challengeRepo, benefitRepo)
+	})
+	r.DELETE("/challenges/:id", func(c *gin.Context) {
+		deleteChallenge(c, challengeRepo)
+	})
+
+	r.GET("/promo-codes", func(c *gin.Context) {
+		getPromoCodes(c, promoRepo)
+	})
+	r.POST("/promo-codes", func(c *gin.Context) {
+		addPromoCode(c, promoRepo)
+	})
+	r.GET("/promo-codes/:id", func(c *gin.Context) {
+		getPromoCode(c, promoRepo)
+	})
+	r.PUT("/promo-codes/:id", func(c *gin.Context) {
+		updatePromoCode(c, promoRepo)
+	})
+	r.DELETE("/promo-codes/:id", func(c *gin.Context) {
+		deletePromoCode(c, promoRepo)
+	})
+
+	r.GET("/api-keys", func(c *gin.Context) {
+		getApiKeys(c, apiKeyRepo)
+	})
+	r.POST("/api-keys", func(c *gin.Context) {
+		createApiKey(c, apiKeyRepo)
+	})
+	r.POST("/api-keys/:id/rotate", func(c *gin.Context) {
+		rotateApiKey(c, apiKeyRepo)
+	})
+	r.DELETE("/api-keys/:id", func(c *gin.Context) {
+		revokeApiKey(c, apiKeyRepo)
+	})
+
+	r.GET("/audit-log", func(c *gin.Context) {
+		getAuditLog(c, auditRepo)
+	})
+
+	r.GET("/webhooks", func(c *gin.Context) {
+		getWebhooks(c, webhookRepo)
+	})
+	r.POST("/webhooks", func(c *gin.Context) {
+		addWebhook(c, webhookRepo, partnerRepo)
+	})
+	r.GET("/webhooks/:id", func(c *gin.Context) {
+		getWebhook(c, webhookRepo)
+	})
+	r.PUT("/webhooks/:id", func(c *gin.Context) {
+		updateWebhook(c, webhookRepo, partnerRepo)
+	})
+	r.DELETE("/webhooks/:id", func(c *gin.Context) {
+		deleteWebhook(c, webhookRepo)
+	})
+	r.GET("/webhooks/:id/deliveries", func(c *gin.Context) {
+		getWebhookDeliveries(c, webhookRepo)
+	})
+	r.POST("/webhooks/:id/deliveries/:delivery_id/replay", func(c *gin.Context) {
+		replayWebhookDelivery(c, webhookRepo)
+	})
+	r.POST("/webhooks/:id/ping", func(c *gin.Context) {
+		pingWebhook(c, webhookRepo, webhookDeliverer)
 	})
 
 	log.Println("Server running on port 8080")
@@ -84,3 +524,11 @@ func main() {
 	}
 
 }
+
+// getEnv returns the environment variable or fallback if it is not set.
+func getEnv(name string, fallback string) string {
+	if value, ok := os.LookupEnv(name); ok {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	migrationsLock    = "migrations"
+	migrationsLockTTL = 30 * time.Minute
+)
+
+// Migration is a one-off change to existing data. It is applied once per
+// database, in the order migrations are listed.
+type Migration struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+type appliedMigration struct {
+	Name      string    `bson:"_id"`
+	AppliedAt time.Time `bson:"appliedAt"`
+}
+
+type MigrationRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMigrationRepository(collection *mongo.Collection) (*MigrationRepository, error) {
+	return &MigrationRepository{
+		collection: collection,
+	}, nil
+}
+
+// IsApplied tells whether the named migration already ran.
+func (r *MigrationRepository) IsApplied(ctx context.Context, name string) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"_id": name})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// MarkApplied records that the named migration ran.
+func (r *MigrationRepository) MarkApplied(ctx context.Context, name string, now time.Time) error {
+	_, err := r.collection.InsertOne(ctx, appliedMigration{Name: name, AppliedAt: now})
+	return err
+}
+
+// migrateFreeTextCategories moves benefits created before the managed
+// taxonomy from their free-text category to a slug, creating a category
+// named after the text where the slug does not exist yet. Text without
+// letters or digits moves to "other".
+func migrateFreeTextCategories(ctx context.Context, categoryRepo *CategoryRepository, benefitRepo *BenefitRepository) error {
+	texts, err := benefitRepo.GetDistinctCategories(ctx)
+	if err != nil {
+		return err
+	}
+	for _, text := range texts {
+		slug, name := slugify(text), text
+		if slug == "" {
+			slug, name = "other", "Other"
+		}
+		if _, err := categoryRepo.GetCategoryBySlug(ctx, slug); err != nil {
+			if err != ErrCategoryNotFound {
+				return err
+			}
+			_, err := categoryRepo.AddCategory(ctx, &Category{Slug: slug, Name: name})
+			if err != nil && err != ErrCategoryExists {
+				return err
+			}
+		}
+		if slug != text {
+			if err := benefitRepo.RenameCategory(ctx, text, slug); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// backfillAllTimeLeaderboard counts tokens granted before the leaderboards
+// existed on the all-time leaderboard, using the lots still in the wallets.
+// Lots granted in or after the first week in which a user's grants were
+// counted are taken as counted already. Spent and expired lots are no
+// longer in the wallets, so their tokens cannot be recovered.
+func backfillAllTimeLeaderboard(ctx context.Context, walletRepo *WalletRepository, leaderboardRepo *LeaderboardRepository) error {
+	firstWeeks, err := leaderboardRepo.FirstTrackedWeeks(ctx)
+	if err != nil {
+		return err
+	}
+	wallets, err := walletRepo.GetAllWallets(ctx)
+	if err != nil {
+		return err
+	}
+	for _, wallet := range wallets {
+		firstWeek, tracked := firstWeeks[wallet.UserId]
+		untracked := 0
+		var lastGrantedAt time.Time
+		for _, lot := range wallet.TokenLots {
+			if tracked && !lot.GrantedAt.Before(firstWeek) {
+				continue
+			}
+			untracked += lot.Amount
+			if lot.GrantedAt.After(lastGrantedAt) {
+				lastGrantedAt = lot.GrantedAt
+			}
+		}
+		if untracked == 0 {
+			continue
+		}
+		if err := leaderboardRepo.AddUntrackedTokens(ctx, wallet.UserId, untracked, lastGrantedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runMigrations applies the migrations that did not run yet. Only the
+// replica holding the lock migrates; the others start without waiting.
+func runMigrations(ctx context.Context, repo *MigrationRepository, lock *MongoLock, migrations []Migration) error {
+	acquired, err := lock.TryAcquire(ctx, migrationsLock, migrationsLockTTL)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		return nil
+	}
+	defer lock.Release(ctx, migrationsLock)
+
+	for _, migration := range migrations {
+		applied, err := repo.IsApplied(ctx, migration.Name)
+		if err != nil {
+			return err
+		}
+		if applied {
+			continue
+		}
+		log.Printf("applying migration %s", migration.Name)
+		if err := migration.Run(ctx); err != nil {
+			return fmt.Errorf("migration %s: %w", migration.Name, err)
+		}
+		if err := repo.MarkApplied(ctx, migration.Name, time.Now()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	outboxDispatcherLock = "outbox-dispatcher"
+	outboxBatchSize      = 100
+	outboxMaxAttempts    = 10
+	outboxRetryBase      = 10 * time.Second
+	outboxRetryMax       = time.Hour
+	// The lease is renewed before every message, so it only has to outlast
+	// the delivery of a single message to all sinks.
+	outboxLeaseTTL = time.Minute
+)
+
+// OutboxDispatcher delivers outbox messages to every sink at least once.
+// A message is retried with exponential backoff until all sinks accepted it
+// or it ran out of attempts. Sinks that already accepted a message are not
+// called again on retries. Only the replica holding the lock dispatches.
+type OutboxDispatcher struct {
+	outbox   *OutboxRepository
+	lock     *MongoLock
+	sinks    map[string]EventPublisher
+	interval time.Duration
+}
+
+func NewOutboxDispatcher(outbox *OutboxRepository, lock *MongoLock, sinks map[string]EventPublisher, interval time.Duration) *OutboxDispatcher {
+	return &OutboxDispatcher{
+		outbox:   outbox,
+		lock:     lock,
+		sinks:    sinks,
+		interval: interval,
+	}
+}
+
+// Run dispatches once immediately and then on every tick until ctx is
+// cancelled.
+func (d *OutboxDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		d.runOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *OutboxDispatcher) runOnce(ctx context.Context) {
+	acquired, err := d.lock.TryAcquire(ctx, outboxDispatcherLock, outboxLeaseTTL)
+	if err != nil {
+		log.Printf("outbox dispatcher failed to acquire lock: %v", err)
+		return
+	}
+	if !acquired {
+		return
+	}
+
+	if err := d.Dispatch(ctx, time.Now()); err != nil {
+		log.Printf("outbox dispatch failed: %v", err)
+	}
+}
+
+// Dispatch delivers the messages that are due at now. It stops as soon as
+// the lease cannot be renewed so two replicas never dispatch at once.
+func (d *OutboxDispatcher) Dispatch(ctx context.Context, now time.Time) error {
+	messages, err := d.outbox.GetDueMessages(ctx, now, outboxBatchSize)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(d.sinks))
+	for name := range d.sinks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, message := range messages {
+		if err := d.lock.Renew(ctx, outboxDispatcherLock, outboxLeaseTTL); err != nil {
+			return err
+		}
+		if err := d.deliver(ctx, message, names, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *OutboxDispatcher) deliver(ctx context.Context, message OutboxMessage, names []string, now time.Time) error {
+	event := message.Event()
+	var failures []string
+	for _, name := range names {
+		if slices.Contains(message.DeliveredTo, name) {
+			continue
+		}
+		if err := d.sinks[name].Publish(ctx, event); err != nil {
+			failures = append(failures, name+": "+err.Error())
+			continue
+		}
+		if err := d.outbox.MarkDelivered(ctx, message.Id, name); err != nil {
+			return err
+		}
+	}
+
+	if len(failures) == 0 {
+		return d.outbox.MarkDispatched(ctx, message.Id, now)
+	}
+
+	attempts := message.Attempts + 1
+	lastError := strings.Join(failures, "; ")
+	if attempts >= outboxMaxAttempts {
+		log.Printf("giving up on outbox message %s (%s) after %d attempts: %s", message.Id.Hex(), message.Type, attempts, lastError)
+		return d.outbox.MarkAttemptFailed(ctx, message.Id, attempts, nil, lastError)
+	}
+	next := now.Add(retryDelay(attempts, outboxRetryBase, outboxRetryMax))
+	return d.outbox.MarkAttemptFailed(ctx, message.Id, attempts, &next, lastError)
+}
+
+// retryDelay doubles the wait after every failed attempt, starting at base
+// and never exceeding limit.
+func retryDelay(attempts int, base time.Duration, limit time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < limit; i++ {
+		delay *= 2
+	}
+	return min(delay, limit)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	OutboxPending    = "pending"
+	OutboxDispatched = "dispatched"
+	OutboxFailed     = "failed"
+)
+
+// Dispatched messages are kept this long before MongoDB removes them.
+const outboxRetention = 7 * 24 * time.Hour
+
+// OutboxMessage is an event waiting to be delivered to the event sinks,
+// together with its delivery state.
+type OutboxMessage struct {
+	Id            primitive.ObjectID `json:"id" bson:"_id"`
+	Type          string             `json:"type" bson:"type"`
+	AggregateId   primitive.ObjectID `json:"aggregateId" bson:"aggregateId"`
+	OccurredAt    time.Time          `json:"occurredAt" bson:"occurredAt"`
+	Payload       bson.M             `json:"payload" bson:"payload"`
+	Status        string             `json:"status" bson:"status" enums:"pending,dispatched,failed"`
+	Attempts      int                `json:"attempts" bson:"attempts"`
+	NextAttemptAt time.Time          `json:"nextAttemptAt" bson:"nextAttemptAt"`
+	DeliveredTo   []string           `json:"deliveredTo" bson:"deliveredTo"`
+	LastError     string             `json:"lastError,omitempty" bson:"lastError,omitempty"`
+	DispatchedAt  *time.Time         `json:"dispatchedAt,omitempty" bson:"dispatchedAt,omitempty"`
+}
+
+func (m *OutboxMessage) Event() Event {
+	return Event{
+		Id:          m.Id,
+		Type:        m.Type,
+		AggregateId: m.AggregateId,
+		OccurredAt:  m.OccurredAt,
+		Payload:     m.Payload,
+	}
+}
+
+// OutboxRepository stores events in the same database as the state they
+// describe so both can be written in one transaction.
+type OutboxRepository struct {
+	collection *mongo.Collection
+}
+
+func NewOutboxRepository(collection *mongo.Collection) (*OutboxRepository, error) {
+	_, err := collection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "nextAttemptAt", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "dispatchedAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(outboxRetention.Seconds())),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &OutboxRepository{
+		collection: collection,
+	}, nil
+}
+
+// Add stores the events as pending messages. Pass the session context to
+// make them part of the caller's transaction.
+func (r *OutboxRepository) Add(ctx context.Context, events ...Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	documents := make([]any, 0, len(events))
+	for _, event := range events {
+		documents = append(documents, bson.M{
+			"_id":           event.Id,
+			"type":          event.Type,
+			"aggregateId":   event.AggregateId,
+			"occurredAt":    event.OccurredAt,
+			"payload":       event.Payload,
+			"status":        OutboxPending,
+			"attempts":      0,
+			"nextAttemptAt": event.OccurredAt,
+			"deliveredTo":   bson.A{},
+		})
+	}
+	_, err := r.collection.InsertMany(ctx, documents)
+	return err
+}
+
+// Publish adds a single event, so the outbox can be used wherever an
+// EventPublisher is expected.
+func (r *OutboxRepository) Publish(ctx context.Context, event Event) error {
+	return r.Add(ctx, event)
+}
+
+// GetDueMessages returns pending messages whose next attempt is due, oldest first.
+func (r *OutboxRepository) GetDueMessages(ctx context.Context, now time.Time, limit int64) ([]OutboxMessage, error) {
+	filter := bson.M{"status": OutboxPending, "nextAttemptAt": bson.M{"$lte": now}}
+	opts := options.Find().SetSort(bson.D{{Key: "occurredAt", Value: 1}}).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	messages := []OutboxMessage{}
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// MarkDelivered records that the message reached the named sink.
+func (r *OutboxRepository) MarkDelivered(ctx context.Context, id primitive.ObjectID, sink string) error {
+	update := bson.M{"$addToSet": bson.M{"deliveredTo": sink}}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+func (r *OutboxRepository) MarkDispatched(ctx context.Context, id primitive.ObjectID, now time.Time) error {
+	update := bson.M{
+		"$set":   bson.M{"status": OutboxDispatched, "dispatchedAt": now},
+		"$unset": bson.M{"lastError": ""},
+	}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// MarkAttemptFailed schedules the next attempt, or gives up on the message
+// if nextAttemptAt is nil.
+func (r *OutboxRepository) MarkAttemptFailed(ctx context.Context, id primitive.ObjectID, attempts int, nextAttemptAt *time.Time, lastError string) error {
+	set := bson.M{"attempts": attempts, "lastError": lastError}
+	if nextAttemptAt != nil {
+		set["nextAttemptAt"] = *nextAttemptAt
+	} else {
+		set["status"] = OutboxFailed
+	}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+	return err
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	partnerKeyHeader     = "X-Partner-Key"
+	partnerKeyPrefix     = "pk_"
+	partnerIdContextKey  = "partnerId"
+	partnerKeyShownChars = 8
+)
+
+// generatePartnerKey returns a new random API key and the hash to store.
+func generatePartnerKey() (string, string, error) {
+	return generateKey(partnerKeyPrefix)
+}
+
+// generateKey returns a new random key with the given prefix and the hash
+// to store.
+func generateKey(prefix string) (string, string, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	key := prefix + hex.EncodeToString(raw)
+	return key, hashKey(key), nil
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// partnerAuth authenticates requests with a partner API key and stores the
+// partner's ID in the context.
+func partnerAuth(repo *PartnerRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(partnerKeyHeader)
+		if key == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing " + partnerKeyHeader + " header"})
+			return
+		}
+
+		credential, err := repo.GetActiveCredentialByHash(c.Request.Context(), hashKey(key))
+		if err != nil {
+			if err == ErrCredentialNotFound {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid partner key"})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Set(partnerIdContextKey, credential.PartnerId)
+		c.Next()
+	}
+}
+
+// authenticatedPartnerId returns the partner set by partnerAuth.
+func authenticatedPartnerId(c *gin.Context) primitive.ObjectID {
+	return c.MustGet(partnerIdContextKey).(primitive.ObjectID)
+}
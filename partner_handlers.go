@@ -0,0 +1,603 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// NewPartnerCredential is returned once when a credential is created. The
+// key cannot be retrieved later.
+type NewPartnerCredential struct {
+	Credential PartnerCredential `json:"credential"`
+	Key        string            `json:"key"`
+}
+
+// @Summary Get partners
+// @Description Retrieves all partners
+// @Tags partners
+// @Accept json
+// @Produce json
+// @Success 200 {array} Partner "List of partners"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /partners [get]
+func getPartners(c *gin.Context, repo *PartnerRepository) {
+	ctx := c.Request.Context()
+	partners, err := repo.GetAllPartners(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, partners)
+}
+
+// @Summary Get a single partner
+// @Description Retrieves a single partner by its ID
+// @Tags partners
+// @Accept json
+// @Produce json
+// @Param id path string true "Partner ID"
+// @Success 200 {object} Partner "Single partner"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 404 {object} ErrorResponse "Partner not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /partners/{id} [get]
+func getPartner(c *gin.Context, repo *PartnerRepository) {
+	ctx := c.Request.Context()
+	partnerId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	partner, err := repo.GetPartnerByID(ctx, partnerId)
+	if err != nil {
+		if err == ErrPartnerNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, partner)
+}
+
+// @Summary Add a new partner
+// @Description Adds a new partner
+// @Tags partners
+// @Accept json
+// @Produce json
+// @Param partner body Partner true "Partner to add"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 201 {object} Partner "Partner created"
+// @Failure 400 {object} ErrorResponse "Invalid partner format"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /partners [post]
+func addPartner(c *gin.Context, repo *PartnerRepository) {
+	ctx := c.Request.Context()
+	var partner Partner
+	if err := c.ShouldBindJSON(&partner); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if partner.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	savedPartner, err := repo.AddPartner(ctx, &partner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAuditChange(c, "partners", savedPartner.Id, nil, savedPartner)
+	c.JSON(http.StatusCreated, savedPartner)
+}
+
+// @Summary Update a partner
+// @Description Updates a partner by its ID
+// @Tags partners
+// @Accept json
+// @Produce json
+// @Param id path string true "Partner ID"
+// @Param partner body Partner true "Updated partner information"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 200 {object} Partner "Partner updated successfully"
+// @Failure 400 {object} ErrorResponse "Invalid ID or partner format"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 404 {object} ErrorResponse "Partner not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /partners/{id} [put]
+func updatePartner(c *gin.Context, repo *PartnerRepository) {
+	ctx := c.Request.Context()
+	partnerId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	existingPartner, err := repo.GetPartnerByID(ctx, partnerId)
+	if err != nil {
+		if err == ErrPartnerNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var updatedPartner Partner
+	if err := c.ShouldBindJSON(&updatedPartner); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if updatedPartner.Id != partnerId {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Partner ID in URL does not match ID in request body"})
+		return
+	}
+	if updatedPartner.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	savedPartner, err := repo.UpdatePartner(ctx, &updatedPartner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAuditChange(c, "partners", partnerId, existingPartner, savedPartner)
+
+	c.JSON(http.StatusOK, savedPartner)
+}
+
+// @Summary Delete a partner
+// @Description Deletes a partner by its ID
+// @Tags partners
+// @Accept json
+// @Produce json
+// @Param id path string true "Partner ID"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 200 {object} object "Partner deleted successfully"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /partners/{id} [delete]
+func deletePartner(c *gin.Context, repo *PartnerRepository) {
+	ctx := c.Request.Context()
+	partnerId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deletedPartner, err := repo.GetPartnerByID(ctx, partnerId)
+	if err != nil && err != ErrPartnerNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := repo.DeletePartner(ctx, partnerId); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if deletedPartner != nil {
+		recordAuditChange(c, "partners", partnerId, deletedPartner, nil)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Partner deleted successfully"})
+}
+
+// @Summary Get partner credentials
+// @Description Lists the API credentials of a partner without their keys
+// @Tags partners
+// @Accept json
+// @Produce json
+// @Param id path string true "Partner ID"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 200 {array} PartnerCredential "List of credentials"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /partners/{id}/credentials [get]
+func getPartnerCredentials(c *gin.Context, repo *PartnerRepository) {
+	ctx := c.Request.Context()
+	partnerId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	credentials, err := repo.GetCredentials(ctx, partnerId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, credentials)
+}
+
+// @Summary Create a partner credential
+// @Description Creates an API key for a partner. The key is only returned in this response.
+// @Tags partners
+// @Accept json
+// @Produce json
+// @Param id path string true "Partner ID"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 201 {object} NewPartnerCredential "Credential with its key"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 404 {object} ErrorResponse "Partner not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /partners/{id}/credentials [post]
+func createPartnerCredential(c *gin.Context, repo *PartnerRepository) {
+	ctx := c.Request.Context()
+	partnerId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := repo.GetPartnerByID(ctx, partnerId); err != nil {
+		if err == ErrPartnerNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, keyHash, err := generatePartnerKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	credential := PartnerCredential{
+		PartnerId: partnerId,
+		KeyPrefix: key[:len(partnerKeyPrefix)+partnerKeyShownChars],
+		KeyHash:   keyHash,
+		CreatedAt: time.Now(),
+	}
+	savedCredential, err := repo.AddCredential(ctx, &credential)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// The credential's key hash is never serialized, so the key stays out
+	// of the audit log
+	recordAuditChange(c, "partner-credentials", savedCredential.Id, nil, savedCredential)
+
+	c.JSON(http.StatusCreated, NewPartnerCredential{Credential: *savedCredential, Key: key})
+}
+
+// @Summary Revoke a partner credential
+// @Description Revokes a partner API key so it can no longer be used
+// @Tags partners
+// @Accept json
+// @Produce json
+// @Param id path string true "Partner ID"
+// @Param credential_id path string true "Credential ID"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 200 {object} object "Credential revoked successfully"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 404 {object} ErrorResponse "Credential not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /partners/{id}/credentials/{credential_id} [delete]
+func revokePartnerCredential(c *gin.Context, repo *PartnerRepository) {
+	ctx := c.Request.Context()
+	partnerId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	credentialId, err := primitive.ObjectIDFromHex(c.Param("credential_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	existingCredential, err := repo.GetCredentialByID(ctx, partnerId, credentialId)
+	if err != nil {
+		if err == ErrCredentialNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := repo.RevokeCredential(ctx, partnerId, credentialId); err != nil {
+		if err == ErrCredentialNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	revokedCredential, err := repo.GetCredentialByID(ctx, partnerId, credentialId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAuditChange(c, "partner-credentials", credentialId, existingCredential, revokedCredential)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Credential revoked successfully"})
+}
+
+// @Summary Get own benefits
+// @Description Retrieves the benefits of the authenticated partner
+// @Tags partner
+// @Accept json
+// @Produce json
+// @Param X-Partner-Key header string true "Partner API key"
+// @Success 200 {array} Benefit "List of benefits"
+// @Failure 401 {object} ErrorResponse "Missing or invalid partner key"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /partner/benefits [get]
+func getPartnerBenefits(c *gin.Context, repo *BenefitRepository) {
+	ctx := c.Request.Context()
+	benefits, err := repo.GetFilteredBenefits(ctx, bson.M{"partnerId": authenticatedPartnerId(c)})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, benefits)
+}
+
+// @Summary Add an own benefit
+// @Description Adds a benefit owned by the authenticated partner
+// @Tags partner
+// @Accept json
+// @Produce json
+// @Param X-Partner-Key header string true "Partner API key"
+// @Param benefit body Benefit true "Benefit to add"
+// @Success 201 {object} Benefit "Benefit created"
+// @Failure 400 {object} ErrorResponse "Invalid benefit format or unknown category"
+// @Failure 401 {object} ErrorResponse "Missing or invalid partner key"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /partner/benefits [post]
+func addPartnerBenefit(c *gin.Context, repo *BenefitRepository, categoryRepo *CategoryRepository) {
+	ctx := c.Request.Context()
+	var benefit Benefit
+	if err := c.ShouldBindJSON(&benefit); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := benefit.ValidateSchedule(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := benefit.ValidateTranslations(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := benefit.ValidateLimits(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateBenefitCategory(ctx, categoryRepo, &benefit); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	partnerId := authenticatedPartnerId(c)
+	benefit.PartnerId = &partnerId
+
+	savedBenefit, err := repo.AddBenefit(ctx, &benefit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAuditChange(c, "benefits", savedBenefit.Id, nil, savedBenefit)
+	c.JSON(http.StatusCreated, savedBenefit)
+}
+
+// @Summary Update an own benefit
+// @Description Updates a benefit owned by the authenticated partner
+// @Tags partner
+// @Accept json
+// @Produce json
+// @Param X-Partner-Key header string true "Partner API key"
+// @Param id path string true "Benefit ID"
+// @Param benefit body Benefit true "Updated benefit information"
+// @Success 200 {object} Benefit "Benefit updated successfully"
+// @Failure 400 {object} ErrorResponse "Invalid ID or benefit format or unknown category"
+// @Failure 401 {object} ErrorResponse "Missing or invalid partner key"
+// @Failure 404 {object} ErrorResponse "Benefit not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /partner/benefits/{id} [put]
+func updatePartnerBenefit(c *gin.Context, repo *BenefitRepository, categoryRepo *CategoryRepository) {
+	ctx := c.Request.Context()
+	benefitId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	partnerId := authenticatedPartnerId(c)
+	existingBenefit, status, err := checkPartnerBenefit(c, repo, benefitId, partnerId)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	var updatedBenefit Benefit
+	if err := c.ShouldBindJSON(&updatedBenefit); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if updatedBenefit.Id != benefitId {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Benefit ID in URL does not match ID in request body"})
+		return
+	}
+	if err := updatedBenefit.ValidateSchedule(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := updatedBenefit.ValidateTranslations(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := updatedBenefit.ValidateLimits(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateBenefitCategory(ctx, categoryRepo, &updatedBenefit); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	updatedBenefit.PartnerId = &partnerId
+
+	savedBenefit, err := repo.UpdateBenefit(ctx, &updatedBenefit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAuditChange(c, "benefits", benefitId, existingBenefit, savedBenefit)
+
+	c.JSON(http.StatusOK, savedBenefit)
+}
+
+// @Summary Delete an own benefit
+// @Description Deletes a benefit owned by the authenticated partner
+// @Tags partner
+// @Accept json
+// @Produce json
+// @Param X-Partner-Key header string true "Partner API key"
+// @Param id path string true "Benefit ID"
+// @Success 200 {object} object "Benefit deleted successfully"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 401 {object} ErrorResponse "Missing or invalid partner key"
+// @Failure 404 {object} ErrorResponse "Benefit not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /partner/benefits/{id} [delete]
+func deletePartnerBenefit(c *gin.Context, repo *BenefitRepository) {
+	ctx := c.Request.Context()
+	benefitId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deletedBenefit, status, err := checkPartnerBenefit(c, repo, benefitId, authenticatedPartnerId(c))
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := repo.DeleteBenefit(ctx, benefitId); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAuditChange(c, "benefits", benefitId, deletedBenefit, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Benefit deleted successfully"})
+}
+
+// checkPartnerBenefit makes sure the benefit exists and belongs to the
+// partner, and returns it. Benefits of other partners are reported as not
+// found.
+func checkPartnerBenefit(c *gin.Context, repo *BenefitRepository, benefitId primitive.ObjectID, partnerId primitive.ObjectID) (*Benefit, int, error) {
+	benefit, err := repo.GetBenefitByID(c.Request.Context(), benefitId)
+	if err != nil {
+		if err == ErrBenefitNotFound {
+			return nil, http.StatusNotFound, err
+		}
+		return nil, http.StatusInternalServerError, err
+	}
+	if benefit.PartnerId == nil || *benefit.PartnerId != partnerId {
+		return nil, http.StatusNotFound, ErrBenefitNotFound
+	}
+	return benefit, http.StatusOK, nil
+}
+
+// @Summary Redeem a voucher
+// @Description Marks a voucher for one of the authenticated partner's benefits as used
+// @Tags partner
+// @Accept json
+// @Produce json
+// @Param X-Partner-Key header string true "Partner API key"
+// @Param code body string true "Voucher code"
+// @Success 200 {object} OwnedBenefit "Redeemed benefit"
+// @Failure 400 {object} ErrorResponse "Invalid request format or voucher code"
+// @Failure 401 {object} ErrorResponse "Missing or invalid partner key"
+// @Failure 404 {object} ErrorResponse "Voucher not found"
+// @Failure 409 {object} ErrorResponse "Voucher expired or already redeemed"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /partner/vouchers/redeem [post]
+func redeemVoucher(c *gin.Context, repo *BenefitRepository, outbox *OutboxRepository) {
+	ctx := c.Request.Context()
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ownedBenefit, err := repo.GetOwnedBenefitByVoucherCode(ctx, strings.ToUpper(strings.TrimSpace(req.Code)))
+	if err != nil {
+		if err == ErrInvalidVoucherCode {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err == ErrOwnedBenefitNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// The partner who sold the voucher honours it, even if the benefit has
+	// since moved to another partner or been deleted. Vouchers of other
+	// partners are reported as not found.
+	partnerId := authenticatedPartnerId(c)
+	if ownedBenefit.PartnerId == nil || *ownedBenefit.PartnerId != partnerId {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrOwnedBenefitNotFound.Error()})
+		return
+	}
+
+	session, err := repo.client.StartSession()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer session.EndSession(ctx)
+
+	transaction := func(sessCtx mongo.SessionContext) (any, error) {
+		if err := repo.RedeemOwnedBenefit(sessCtx, ownedBenefit, time.Now()); err != nil {
+			return nil, err
+		}
+		event := NewEvent(EventBenefitRedeemed, ownedBenefit.Id, BenefitRedeemedPayload{
+			OwnedBenefitId: ownedBenefit.Id,
+			BenefitId:      ownedBenefit.BenefitId,
+			UserId:         ownedBenefit.OwnerId,
+			PartnerId:      ownedBenefit.PartnerId,
+			RedeemedAt:     *ownedBenefit.RedeemedAt,
+		})
+		return nil, outbox.Add(sessCtx, event)
+	}
+
+	if _, err := session.WithTransaction(ctx, transaction); err != nil {
+		if err == ErrVoucherNotRedeemable {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, ownedBenefit)
+}
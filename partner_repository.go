@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrPartnerNotFound = errors.New("partner not found")
+var ErrCredentialNotFound = errors.New("credential not found")
+
+type PartnerLocation struct {
+	Name      string  `json:"name" bson:"name"`
+	Address   string  `json:"address" bson:"address"`
+	Latitude  float64 `json:"latitude" bson:"latitude"`
+	Longitude float64 `json:"longitude" bson:"longitude"`
+}
+
+// Partner is a vendor that provides benefits.
+type Partner struct {
+	Id           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name         string             `json:"name" bson:"name"`
+	ContactName  string             `json:"contactName" bson:"contactName"`
+	ContactEmail string             `json:"contactEmail" bson:"contactEmail"`
+	ContactPhone string             `json:"contactPhone" bson:"contactPhone"`
+	Locations    []PartnerLocation  `json:"locations" bson:"locations"`
+	LogoUrl      string             `json:"logoUrl" bson:"logoUrl"`
+}
+
+// PartnerCredential is an API key that lets a partner manage its own
+// benefits and redeem its own vouchers. Only a hash of the key is stored.
+type PartnerCredential struct {
+	Id        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	PartnerId primitive.ObjectID `json:"partnerId" bson:"partnerId"`
+	KeyPrefix string             `json:"keyPrefix" bson:"keyPrefix"`
+	KeyHash   string             `json:"-" bson:"keyHash"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+	RevokedAt *time.Time         `json:"revokedAt,omitempty" bson:"revokedAt,omitempty"`
+}
+
+type PartnerRepository struct {
+	collection           *mongo.Collection
+	credentialCollection *mongo.Collection
+}
+
+func NewPartnerRepository(collection *mongo.Collection, credentialCollection *mongo.Collection) (*PartnerRepository, error) {
+	_, err := credentialCollection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "keyHash", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &PartnerRepository{
+		collection:           collection,
+		credentialCollection: credentialCollection,
+	}, nil
+}
+
+func (r *PartnerRepository) GetAllPartners(ctx context.Context) ([]Partner, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var partners []Partner
+	for cursor.Next(ctx) {
+		var partner Partner
+		err := cursor.Decode(&partner)
+		if err != nil {
+			return nil, err
+		}
+		partners = append(partners, partner)
+	}
+
+	return partners, nil
+}
+
+func (r *PartnerRepository) GetPartnerByID(ctx context.Context, id primitive.ObjectID) (*Partner, error) {
+	var partner Partner
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&partner)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrPartnerNotFound
+		}
+		return nil, err
+	}
+
+	return &partner, nil
+}
+
+func (r *PartnerRepository) AddPartner(ctx context.Context, partner *Partner) (*Partner, error) {
+	result, err := r.collection.InsertOne(ctx, partner)
+	if err != nil {
+		return nil, err
+	}
+	generatedID, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return nil, errors.New("failed to convert InsertedID to ObjectID")
+	}
+	partner.Id = generatedID
+	return partner, nil
+}
+
+func (r *PartnerRepository) UpdatePartner(ctx context.Context, partner *Partner) (*Partner, error) {
+	filter := bson.M{"_id": partner.Id}
+	update := bson.M{"$set": partner}
+	_, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return nil, err
+	}
+
+	return partner, nil
+}
+
+func (r *PartnerRepository) DeletePartner(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+func (r *PartnerRepository) AddCredential(ctx context.Context, credential *PartnerCredential) (*PartnerCredential, error) {
+	result, err := r.credentialCollection.InsertOne(ctx, credential)
+	if err != nil {
+		return nil, err
+	}
+	generatedID, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return nil, errors.New("failed to convert InsertedID to ObjectID")
+	}
+	credential.Id = generatedID
+	return credential, nil
+}
+
+func (r *PartnerRepository) GetCredentials(ctx context.Context, partnerId primitive.ObjectID) ([]PartnerCredential, error) {
+	cursor, err := r.credentialCollection.Find(ctx, bson.M{"partnerId": partnerId})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var credentials []PartnerCredential
+	for cursor.Next(ctx) {
+		var credential PartnerCredential
+		err := cursor.Decode(&credential)
+		if err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, credential)
+	}
+
+	return credentials, nil
+}
+
+// GetActiveCredentialByHash finds a credential that has not been revoked.
+func (r *PartnerRepository) GetActiveCredentialByHash(ctx context.Context, keyHash string) (*PartnerCredential, error) {
+	var credential PartnerCredential
+	filter := bson.M{"keyHash": keyHash, "revokedAt": bson.M{"$exists": false}}
+	err := r.credentialCollection.FindOne(ctx, filter).Decode(&credential)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrCredentialNotFound
+		}
+		return nil, err
+	}
+
+	return &credential, nil
+}
+
+func (r *PartnerRepository) GetCredentialByID(ctx context.Context, partnerId primitive.ObjectID, id primitive.ObjectID) (*PartnerCredential, error) {
+	var credential PartnerCredential
+	err := r.credentialCollection.FindOne(ctx, bson.M{"_id": id, "partnerId": partnerId}).Decode(&credential)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrCredentialNotFound
+		}
+		return nil, err
+	}
+
+	return &credential, nil
+}
+
+func (r *PartnerRepository) RevokeCredential(ctx context.Context, partnerId primitive.ObjectID, id primitive.ObjectID) error {
+	filter := bson.M{"_id": id, "partnerId": partnerId, "revokedAt": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"revokedAt": time.Now()}}
+	result, err := r.credentialCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrCredentialNotFound
+	}
+	return nil
+}
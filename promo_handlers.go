@@ -0,0 +1,194 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// @Summary Get promo codes
+// @Description Retrieves all promo codes
+// @Tags promo-codes
+// @Accept json
+// @Produce json
+// @Success 200 {array} PromoCode "List of promo codes"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /promo-codes [get]
+func getPromoCodes(c *gin.Context, repo *PromoCodeRepository) {
+	ctx := c.Request.Context()
+	promoCodes, err := repo.GetAllPromoCodes(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, promoCodes)
+}
+
+// @Summary Get a single promo code
+// @Description Retrieves a single promo code by its ID
+// @Tags promo-codes
+// @Accept json
+// @Produce json
+// @Param id path string true "Promo code ID"
+// @Success 200 {object} PromoCode "Single promo code"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 404 {object} ErrorResponse "Promo code not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /promo-codes/{id} [get]
+func getPromoCode(c *gin.Context, repo *PromoCodeRepository) {
+	ctx := c.Request.Context()
+	promoCodeId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	promoCode, err := repo.GetPromoCodeByID(ctx, promoCodeId)
+	if err != nil {
+		if err == ErrPromoCodeNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, promoCode)
+}
+
+// @Summary Add a new promo code
+// @Description Creates a percentage or fixed token discount code, optionally scoped to categories or benefits
+// @Tags promo-codes
+// @Accept json
+// @Produce json
+// @Param promoCode body PromoCode true "Promo code to add"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 201 {object} PromoCode "Promo code created"
+// @Failure 400 {object} ErrorResponse "Invalid promo code format"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 409 {object} ErrorResponse "Promo code already exists"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /promo-codes [post]
+func addPromoCode(c *gin.Context, repo *PromoCodeRepository) {
+	ctx := c.Request.Context()
+	var promoCode PromoCode
+	if err := c.ShouldBindJSON(&promoCode); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := promoCode.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	savedPromoCode, err := repo.AddPromoCode(ctx, &promoCode)
+	if err != nil {
+		if err == ErrPromoCodeExists {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAuditChange(c, "promo-codes", savedPromoCode.Id, nil, savedPromoCode)
+	c.JSON(http.StatusCreated, savedPromoCode)
+}
+
+// @Summary Update a promo code
+// @Description Updates a promo code by its ID, keeping its usage counter
+// @Tags promo-codes
+// @Accept json
+// @Produce json
+// @Param id path string true "Promo code ID"
+// @Param promoCode body PromoCode true "Updated promo code"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 200 {object} PromoCode "Promo code updated successfully"
+// @Failure 400 {object} ErrorResponse "Invalid ID or promo code format"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 404 {object} ErrorResponse "Promo code not found"
+// @Failure 409 {object} ErrorResponse "Promo code already exists"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /promo-codes/{id} [put]
+func updatePromoCode(c *gin.Context, repo *PromoCodeRepository) {
+	ctx := c.Request.Context()
+	promoCodeId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	existingPromoCode, err := repo.GetPromoCodeByID(ctx, promoCodeId)
+	if err != nil {
+		if err == ErrPromoCodeNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var updatedPromoCode PromoCode
+	if err := c.ShouldBindJSON(&updatedPromoCode); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	updatedPromoCode.Id = promoCodeId
+	if err := updatedPromoCode.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	savedPromoCode, err := repo.UpdatePromoCode(ctx, &updatedPromoCode)
+	if err != nil {
+		if err == ErrPromoCodeExists {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAuditChange(c, "promo-codes", promoCodeId, existingPromoCode, savedPromoCode)
+
+	c.JSON(http.StatusOK, savedPromoCode)
+}
+
+// @Summary Delete a promo code
+// @Description Deletes a promo code by its ID
+// @Tags promo-codes
+// @Accept json
+// @Produce json
+// @Param id path string true "Promo code ID"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 200 {object} object "Promo code deleted successfully"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /promo-codes/{id} [delete]
+func deletePromoCode(c *gin.Context, repo *PromoCodeRepository) {
+	ctx := c.Request.Context()
+	promoCodeId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deletedPromoCode, err := repo.GetPromoCodeByID(ctx, promoCodeId)
+	if err != nil && err != ErrPromoCodeNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := repo.DeletePromoCode(ctx, promoCodeId); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if deletedPromoCode != nil {
+		recordAuditChange(c, "promo-codes", promoCodeId, deletedPromoCode, nil)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Promo code deleted successfully"})
+}
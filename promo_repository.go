@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	DiscountPercentage = "percentage"
+	DiscountFixed      = "fixed"
+)
+
+var ErrPromoCodeNotFound = errors.New("promo code not found")
+var ErrPromoCodeExists = errors.New("promo code already exists")
+var ErrPromoCodeInvalid = errors.New("invalid promo code")
+var ErrPromoCodeNotApplicable = errors.New("promo code does not apply to this benefit")
+var ErrPromoCodeUsedUp = errors.New("promo code usage limit reached")
+
+type PromoCode struct {
+	Id             primitive.ObjectID   `json:"id" bson:"_id,omitempty"`
+	Code           string               `json:"code" bson:"code"`
+	DiscountType   string               `json:"discountType" bson:"discountType" enums:"percentage,fixed"`
+	DiscountValue  int                  `json:"discountValue" bson:"discountValue"`
+	Categories     []string             `json:"categories" bson:"categories"`
+	BenefitIds     []primitive.ObjectID `json:"benefitIds" bson:"benefitIds" swaggertype:"array,string"`
+	MaxUses        int                  `json:"maxUses" bson:"maxUses"`
+	MaxUsesPerUser int                  `json:"maxUsesPerUser" bson:"maxUsesPerUser"`
+	Uses           int                  `json:"uses" bson:"uses"`
+	ValidFrom      time.Time            `json:"validFrom" bson:"validFrom"`
+	ValidUntil     time.Time            `json:"validUntil" bson:"validUntil"`
+}
+
+// normalizePromoCode makes code lookups case-insensitive.
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (p *PromoCode) Validate() error {
+	if p.Code == "" {
+		return errors.New("code is required")
+	}
+	switch p.DiscountType {
+	case DiscountPercentage:
+		if p.DiscountValue < 1 || p.DiscountValue > 100 {
+			return errors.New("percentage discount must be between 1 and 100")
+		}
+	case DiscountFixed:
+		if p.DiscountValue < 1 {
+			return errors.New("fixed discount must be positive")
+		}
+	default:
+		return errors.New("discountType must be percentage or fixed")
+	}
+	if p.MaxUses < 0 || p.MaxUsesPerUser < 0 {
+		return errors.New("maxUses and maxUsesPerUser must not be negative")
+	}
+	if !p.ValidFrom.IsZero() && !p.ValidUntil.IsZero() && !p.ValidUntil.After(p.ValidFrom) {
+		return errors.New("validUntil must be after validFrom")
+	}
+	return nil
+}
+
+// CheckApplicable verifies that the code can be used for the benefit at the
+// given time. Usage caps are enforced separately.
+func (p *PromoCode) CheckApplicable(benefit *Benefit, now time.Time) error {
+	if !p.ValidFrom.IsZero() && now.Before(p.ValidFrom) {
+		return ErrPromoCodeInvalid
+	}
+	if !p.ValidUntil.IsZero() && !now.Before(p.ValidUntil) {
+		return ErrPromoCodeInvalid
+	}
+	if len(p.BenefitIds) == 0 && len(p.Categories) == 0 {
+		return nil
+	}
+	if slices.Contains(p.BenefitIds, benefit.Id) {
+		return nil
+	}
+	for _, category := range p.Categories {
+		if strings.EqualFold(category, benefit.Category) {
+			return nil
+		}
+	}
+	return ErrPromoCodeNotApplicable
+}
+
+// Apply returns the price after the discount. The price never drops below zero.
+func (p *PromoCode) Apply(price int) int {
+	switch p.DiscountType {
+	case DiscountPercentage:
+		return price - price*p.DiscountValue/100
+	case DiscountFixed:
+		return max(price-p.DiscountValue, 0)
+	default:
+		return price
+	}
+}
+
+type PromoCodeRepository struct {
+	collection *mongo.Collection
+}
+
+func NewPromoCodeRepository(collection *mongo.Collection) (*PromoCodeRepository, error) {
+	_, err := collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "code", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &PromoCodeRepository{
+		collection: collection,
+	}, nil
+}
+
+func (r *PromoCodeRepository) GetAllPromoCodes(ctx context.Context) ([]PromoCode, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var promoCodes []PromoCode
+	for cursor.Next(ctx) {
+		var promoCode PromoCode
+		err := cursor.Decode(&promoCode)
+		if err != nil {
+			return nil, err
+		}
+		promoCodes = append(promoCodes, promoCode)
+	}
+
+	return promoCodes, nil
+}
+
+func (r *PromoCodeRepository) GetPromoCodeByID(ctx context.Context, id primitive.ObjectID) (*PromoCode, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *PromoCodeRepository) GetPromoCodeByCode(ctx context.Context, code string) (*PromoCode, error) {
+	return r.findOne(ctx, bson.M{"code": normalizePromoCode(code)})
+}
+
+func (r *PromoCodeRepository) findOne(ctx context.Context, filter bson.M) (*PromoCode, error) {
+	var promoCode PromoCode
+	err := r.collection.FindOne(ctx, filter).Decode(&promoCode)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrPromoCodeNotFound
+		}
+		return nil, err
+	}
+
+	return &promoCode, nil
+}
+
+func (r *PromoCodeRepository) AddPromoCode(ctx context.Context, promoCode *PromoCode) (*PromoCode, error) {
+	promoCode.Code = normalizePromoCode(promoCode.Code)
+	promoCode.Uses = 0
+	result, err := r.collection.InsertOne(ctx, promoCode)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrPromoCodeExists
+		}
+		return nil, err
+	}
+	generatedID, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return nil, errors.New("failed to convert InsertedID to ObjectID")
+	}
+	promoCode.Id = generatedID
+	return promoCode, nil
+}
+
+// UpdatePromoCode replaces the code's settings but keeps its usage counter.
+func (r *PromoCodeRepository) UpdatePromoCode(ctx context.Context, promoCode *PromoCode) (*PromoCode, error) {
+	promoCode.Code = normalizePromoCode(promoCode.Code)
+	filter := bson.M{"_id": promoCode.Id}
+	update := bson.M{"$set": bson.M{
+		"code":           promoCode.Code,
+		"discountType":   promoCode.DiscountType,
+		"discountValue":  promoCode.DiscountValue,
+		"categories":     promoCode.Categories,
+		"benefitIds":     promoCode.BenefitIds,
+		"maxUses":        promoCode.MaxUses,
+		"maxUsesPerUser": promoCode.MaxUsesPerUser,
+		"validFrom":      promoCode.ValidFrom,
+		"validUntil":     promoCode.ValidUntil,
+	}}
+	_, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrPromoCodeExists
+		}
+		return nil, err
+	}
+
+	return r.GetPromoCodeByID(ctx, promoCode.Id)
+}
+
+func (r *PromoCodeRepository) DeletePromoCode(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// IncrementUses records count redemptions of the code, failing if that would
+// exceed its global usage cap.
+func (r *PromoCodeRepository) IncrementUses(ctx context.Context, promoCode *PromoCode, count int) error {
+	filter := bson.M{"_id": promoCode.Id}
+	if promoCode.MaxUses > 0 {
+		filter["uses"] = bson.M{"$lte": promoCode.MaxUses - count}
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"uses": count}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrPromoCodeUsedUp
+	}
+	promoCode.Uses += count
+	return nil
+}
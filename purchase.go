@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// maxPurchaseQuantity is the most units of a benefit bought in one request.
+const maxPurchaseQuantity = 100
+
+// Purchase describes units of a benefit a user buys. StockHeld is set when
+// the units were already taken out of stock by a reservation.
+type Purchase struct {
+	BenefitId primitive.ObjectID
+	UserId    primitive.ObjectID
+	Quantity  int
+	PromoCode string
+	StockHeld bool
+}
+
+// purchaseBenefit buys the units inside the session's transaction: it checks
+// availability and limits, applies the promo code, takes the units out of
+// stock, debits the wallet, creates one owned benefit per unit and records
+// a BenefitPurchased event for each in the outbox.
+func purchaseBenefit(sessCtx mongo.SessionContext, benefitRepo *BenefitRepository, walletRepo *WalletRepository, promoRepo *PromoCodeRepository, outbox *OutboxRepository, purchase Purchase) ([]OwnedBenefit, error) {
+	// Step 1: Get Benefit by ID
+	benefit, err := benefitRepo.GetBenefitByID(sessCtx, purchase.BenefitId)
+	if err != nil {
+		return nil, err
+	}
+	if !benefit.IsAvailable(time.Now()) {
+		return nil, ErrBenefitUnavailable
+	}
+
+	// Step 2: Enforce purchase limits
+	if err := benefitRepo.ClaimPurchases(sessCtx, benefit, purchase.UserId, purchase.Quantity, time.Now()); err != nil {
+		return nil, err
+	}
+
+	// Step 3: Apply the promo code, if any
+	unitPrice := benefit.Price
+	var promoCode *PromoCode
+	if purchase.PromoCode != "" {
+		promoCode, err = redeemPromoCode(sessCtx, promoRepo, benefitRepo, purchase.PromoCode, benefit, purchase.UserId, purchase.Quantity)
+		if err != nil {
+			return nil, err
+		}
+		unitPrice = promoCode.Apply(unitPrice)
+	}
+
+	// Step 4: Take the units out of stock unless a reservation already holds them
+	if !purchase.StockHeld {
+		if err := benefitRepo.DecrementStock(sessCtx, purchase.BenefitId, purchase.Quantity); err != nil {
+			return nil, err
+		}
+	}
+
+	// Step 5: Get Wallet by User ID
+	wallet, err := walletRepo.GetWalletByUserID(sessCtx, purchase.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 6: Check and update wallet balance, spending the oldest tokens first
+	if err := wallet.DebitTokens(unitPrice * purchase.Quantity); err != nil {
+		return nil, err
+	}
+	_, err = walletRepo.UpdateWallet(sessCtx, wallet)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 7: Add one purchased benefit with its own voucher code per unit
+	ownedBenefits := make([]OwnedBenefit, 0, purchase.Quantity)
+	for range purchase.Quantity {
+		voucherCode, err := generateVoucherCode()
+		if err != nil {
+			return nil, err
+		}
+		ownedBenefit := OwnedBenefit{
+			OwnerId:        purchase.UserId,
+			BenefitId:      purchase.BenefitId,
+			Purchased:      time.Now(),
+			Content:        voucherCode,
+			VoucherCode:    voucherCode,
+			ExpirationDate: benefit.ExpirationDate,
+			PricePaid:      unitPrice,
+			PartnerId:      benefit.PartnerId,
+		}
+		if promoCode != nil {
+			ownedBenefit.PromoCode = promoCode.Code
+		}
+		savedOwnedBenefit, err := benefitRepo.AddPurchasedBenefit(sessCtx, &ownedBenefit)
+		if err != nil {
+			return nil, err
+		}
+		ownedBenefits = append(ownedBenefits, *savedOwnedBenefit)
+	}
+
+	// Step 8: Let other services know about the purchase
+	if err := outbox.Add(sessCtx, benefitPurchasedEvents(ownedBenefits)...); err != nil {
+		return nil, err
+	}
+
+	return ownedBenefits, nil
+}
+
+// redeemPromoCode checks that the code can be used by the user for the
+// given number of units of the benefit and counts one use per unit.
+func redeemPromoCode(ctx context.Context, promoRepo *PromoCodeRepository, benefitRepo *BenefitRepository, code string, benefit *Benefit, userId primitive.ObjectID, quantity int) (*PromoCode, error) {
+	promoCode, err := promoRepo.GetPromoCodeByCode(ctx, code)
+	if err != nil {
+		if err == ErrPromoCodeNotFound {
+			return nil, ErrPromoCodeInvalid
+		}
+		return nil, err
+	}
+	if err := promoCode.CheckApplicable(benefit, time.Now()); err != nil {
+		return nil, err
+	}
+	if promoCode.MaxUsesPerUser > 0 {
+		used, err := benefitRepo.CountOwnedBenefits(ctx, bson.M{"ownerId": userId, "promoCode": promoCode.Code})
+		if err != nil {
+			return nil, err
+		}
+		if used+quantity > promoCode.MaxUsesPerUser {
+			return nil, ErrPromoCodeUsedUp
+		}
+	}
+	if err := promoRepo.IncrementUses(ctx, promoCode, quantity); err != nil {
+		return nil, err
+	}
+	return promoCode, nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var ErrPurchaseLimitReached = errors.New("purchase limit reached")
+var ErrInvalidPurchaseLimits = errors.New("invalid purchase limits")
+
+// PurchaseLimits restricts how many units of a benefit can be bought. A zero
+// value means the corresponding limit is not enforced. Windows are fixed,
+// consecutive blocks of WindowHours hours and days are calendar days in the
+// city's time zone.
+type PurchaseLimits struct {
+	MaxPerUser          int `json:"maxPerUser" bson:"maxPerUser"`
+	MaxPerUserPerWindow int `json:"maxPerUserPerWindow" bson:"maxPerUserPerWindow"`
+	WindowHours         int `json:"windowHours" bson:"windowHours"`
+	MaxPerDay           int `json:"maxPerDay" bson:"maxPerDay"`
+}
+
+// ValidateLimits checks that the purchase limits, if any, are consistent.
+func (b *Benefit) ValidateLimits() error {
+	limits := b.Limits
+	if limits == nil {
+		return nil
+	}
+	if limits.MaxPerUser < 0 || limits.MaxPerUserPerWindow < 0 || limits.WindowHours < 0 || limits.MaxPerDay < 0 {
+		return fmt.Errorf("%w: limits must not be negative", ErrInvalidPurchaseLimits)
+	}
+	if limits.MaxPerUserPerWindow > 0 && limits.WindowHours == 0 {
+		return fmt.Errorf("%w: maxPerUserPerWindow requires windowHours", ErrInvalidPurchaseLimits)
+	}
+	return nil
+}
+
+// PurchaseAllowance tells how many more units of a benefit a user may buy.
+// Remaining is only meaningful when Limited is true.
+type PurchaseAllowance struct {
+	BenefitId primitive.ObjectID `json:"benefitId"`
+	UserId    primitive.ObjectID `json:"userId"`
+	Limited   bool               `json:"limited"`
+	Remaining int                `json:"remaining"`
+}
+
+// purchaseCounter holds how many units of a benefit were bought in one
+// scope of a limit. Counters of windows and days expire once they are over.
+type purchaseCounter struct {
+	Id        string     `bson:"_id"`
+	Count     int        `bson:"count"`
+	ExpiresAt *time.Time `bson:"expiresAt,omitempty"`
+}
+
+// purchaseScope is one enforced limit of a benefit. Owned benefits matching
+// filter seed the counter the first time the scope is used.
+type purchaseScope struct {
+	key       string
+	limit     int
+	filter    bson.M
+	expiresAt *time.Time
+}
+
+// purchaseScopes lists the counters a purchase of the benefit by the user
+// at the given time is checked against.
+func purchaseScopes(benefit *Benefit, userId primitive.ObjectID, now time.Time) []purchaseScope {
+	limits := benefit.Limits
+	if limits == nil {
+		return nil
+	}
+
+	var scopes []purchaseScope
+	if limits.MaxPerUser > 0 {
+		scopes = append(scopes, purchaseScope{
+			key:    fmt.Sprintf("%s:user:%s", benefit.Id.Hex(), userId.Hex()),
+			limit:  limits.MaxPerUser,
+			filter: bson.M{"benefitId": benefit.Id, "ownerId": userId},
+		})
+	}
+	if limits.MaxPerUserPerWindow > 0 && limits.WindowHours > 0 {
+		window := time.Duration(limits.WindowHours) * time.Hour
+		windowStart := now.Truncate(window)
+		windowEnd := windowStart.Add(window)
+		scopes = append(scopes, purchaseScope{
+			key:       fmt.Sprintf("%s:user:%s:window:%dh:%d", benefit.Id.Hex(), userId.Hex(), limits.WindowHours, windowStart.Unix()),
+			limit:     limits.MaxPerUserPerWindow,
+			filter:    bson.M{"benefitId": benefit.Id, "ownerId": userId, "purchased": bson.M{"$gte": windowStart, "$lt": windowEnd}},
+			expiresAt: &windowEnd,
+		})
+	}
+	if limits.MaxPerDay > 0 {
+		local := now.In(cityLocation)
+		dayStart := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, cityLocation)
+		dayEnd := dayStart.AddDate(0, 0, 1)
+		scopes = append(scopes, purchaseScope{
+			key:       fmt.Sprintf("%s:day:%s", benefit.Id.Hex(), dayStart.Format(time.DateOnly)),
+			limit:     limits.MaxPerDay,
+			filter:    bson.M{"benefitId": benefit.Id, "purchased": bson.M{"$gte": dayStart, "$lt": dayEnd}},
+			expiresAt: &dayEnd,
+		})
+	}
+	return scopes
+}
+
+// countPurchases reads the counter of the scope, falling back to counting
+// owned benefits if the scope has not been used yet.
+func (r *BenefitRepository) countPurchases(ctx context.Context, scope purchaseScope) (int, bool, error) {
+	var counter purchaseCounter
+	err := r.purchaseCounterCollection.FindOne(ctx, bson.M{"_id": scope.key}).Decode(&counter)
+	if err == nil {
+		return counter.Count, true, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return 0, false, err
+	}
+	count, err := r.CountOwnedBenefits(ctx, scope.filter)
+	return count, false, err
+}
+
+// RemainingPurchases computes how many more units of the benefit the user
+// may buy at the given time.
+func (r *BenefitRepository) RemainingPurchases(ctx context.Context, benefit *Benefit, userId primitive.ObjectID, now time.Time) (*PurchaseAllowance, error) {
+	allowance := &PurchaseAllowance{
+		BenefitId: benefit.Id,
+		UserId:    userId,
+	}
+	for _, scope := range purchaseScopes(benefit, userId, now) {
+		count, _, err := r.countPurchases(ctx, scope)
+		if err != nil {
+			return nil, err
+		}
+		remaining := max(scope.limit-count, 0)
+		if !allowance.Limited || remaining < allowance.Remaining {
+			allowance.Remaining = remaining
+		}
+		allowance.Limited = true
+	}
+	return allowance, nil
+}
+
+// ClaimPurchases counts quantity units of the benefit against every limit
+// that applies to the user, failing with ErrPurchaseLimitReached if one of
+// them would be exceeded. It must run inside the purchase transaction: the
+// counters are only incremented while they stay within the limit, so
+// concurrent purchases cannot oversell.
+func (r *BenefitRepository) ClaimPurchases(ctx context.Context, benefit *Benefit, userId primitive.ObjectID, quantity int, now time.Time) error {
+	for _, scope := range purchaseScopes(benefit, userId, now) {
+		filter := bson.M{"_id": scope.key, "count": bson.M{"$lte": scope.limit - quantity}}
+		result, err := r.purchaseCounterCollection.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"count": quantity}})
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount > 0 {
+			continue
+		}
+
+		// Either the counter is full or the scope is used for the first time
+		count, exists, err := r.countPurchases(ctx, scope)
+		if err != nil {
+			return err
+		}
+		if exists || count+quantity > scope.limit {
+			return ErrPurchaseLimitReached
+		}
+		counter := purchaseCounter{Id: scope.key, Count: count + quantity, ExpiresAt: scope.expiresAt}
+		if _, err := r.purchaseCounterCollection.InsertOne(ctx, counter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit is a token bucket refilled at Rate tokens per second that
+// holds at most Burst tokens. Every request takes one token.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// parseRateLimit reads limits such as "10/m" or "10/m:20", the latter
+// allowing bursts of 20 requests. The unit is s, m or h. An empty spec or
+// "off" means no limit and returns nil.
+func parseRateLimit(spec string) (*RateLimit, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" || spec == "off" {
+		return nil, nil
+	}
+	rateSpec, burstSpec, hasBurst := strings.Cut(spec, ":")
+	countSpec, unit, found := strings.Cut(rateSpec, "/")
+	if !found {
+		return nil, fmt.Errorf("invalid rate limit %q", spec)
+	}
+	count, err := strconv.Atoi(countSpec)
+	if err != nil || count <= 0 {
+		return nil, fmt.Errorf("invalid rate limit %q", spec)
+	}
+	per := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour}[unit]
+	if per == 0 {
+		return nil, fmt.Errorf("invalid rate limit unit in %q", spec)
+	}
+	limit := &RateLimit{Rate: float64(count) / per.Seconds(), Burst: count}
+	if hasBurst {
+		limit.Burst, err = strconv.Atoi(burstSpec)
+		if err != nil || limit.Burst <= 0 {
+			return nil, fmt.Errorf("invalid rate limit burst in %q", spec)
+		}
+	}
+	return limit, nil
+}
+
+// refillTime is how long an empty bucket takes to fill up.
+func (l RateLimit) refillTime() time.Duration {
+	return time.Duration(float64(l.Burst) / l.Rate * float64(time.Second))
+}
+
+// retryAfter is how long until a bucket holding tokens has a whole token.
+func (l RateLimit) retryAfter(tokens float64) time.Duration {
+	return time.Duration((1 - tokens) / l.Rate * float64(time.Second))
+}
+
+type RateLimitStore interface {
+	// Take removes a token from the key's bucket. If the bucket is empty it
+	// returns false and how long until the next token.
+	Take(ctx context.Context, key string, limit RateLimit, now time.Time) (bool, time.Duration, error)
+}
+
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	fullAt    time.Time
+}
+
+// MemoryRateLimitStore keeps buckets in the process. It suits a single
+// replica; with several, each replica allows the full rate.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: map[string]*memoryBucket{}}
+}
+
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, limit RateLimit, now time.Time) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+	bucket, found := s.buckets[key]
+	if !found {
+		bucket = &memoryBucket{tokens: float64(limit.Burst), updatedAt: now}
+		s.buckets[key] = bucket
+	}
+	elapsed := max(now.Sub(bucket.updatedAt).Seconds(), 0)
+	bucket.tokens = math.Min(float64(limit.Burst), bucket.tokens+elapsed*limit.Rate)
+	bucket.updatedAt = now
+	bucket.fullAt = now.Add(limit.refillTime())
+	if bucket.tokens < 1 {
+		return false, limit.retryAfter(bucket.tokens), nil
+	}
+	bucket.tokens--
+	return true, 0, nil
+}
+
+// sweep forgets buckets that have filled up again, at most once a minute.
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, bucket := range s.buckets {
+		if now.After(bucket.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// RateLimitKey limits requests sharing the value returned by Key, e.g.
+// the same client IP. An empty value is not limited.
+type RateLimitKey struct {
+	Name  string
+	Limit *RateLimit
+	Key   func(c *gin.Context) string
+}
+
+// rateLimit rejects requests to the route with 429 Too Many Requests once
+// any of the keys' buckets is empty. If the store fails, requests are let
+// through rather than taking the route down.
+func rateLimit(store RateLimitStore, route string, keys ...RateLimitKey) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		now := time.Now()
+		for _, key := range keys {
+			if key.Limit == nil {
+				continue
+			}
+			value := key.Key(c)
+			if value == "" {
+				continue
+			}
+			allowed, retryAfter, err := store.Take(c.Request.Context(), route+":"+key.Name+":"+value, *key.Limit, now)
+			if err != nil {
+				log.Printf("rate limit: %s for %s: %v", route, key.Name, err)
+				continue
+			}
+			if !allowed {
+				c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded, try again later"})
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// clientIPKey returns the client's address, which X-Forwarded-For only
+// overrides when sent by one of the router's trusted proxies.
+func clientIPKey(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// requestUserKey returns the user a request is for, from the user_id path
+// parameter or JSON body field. The body is put back for the handler.
+// Callers choose the user ID freely, so a client can spread requests over
+// made-up users; only the IP bucket bounds abuse by a single client.
+func requestUserKey(c *gin.Context) string {
+	if userId := c.Param("user_id"); userId != "" {
+		return userId
+	}
+	if c.Request.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	var req struct {
+		UserID string `json:"user_id"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return ""
+	}
+	return req.UserID
+}
+
+// userAndIPLimits builds the per-user and per-IP keys of a group of routes
+// from limit specs such as "10/m".
+func userAndIPLimits(userSpec string, ipSpec string) ([]RateLimitKey, error) {
+	userLimit, err := parseRateLimit(userSpec)
+	if err != nil {
+		return nil, err
+	}
+	ipLimit, err := parseRateLimit(ipSpec)
+	if err != nil {
+		return nil, err
+	}
+	return []RateLimitKey{
+		{Name: "user", Limit: userLimit, Key: requestUserKey},
+		{Name: "ip", Limit: ipLimit, Key: clientIPKey},
+	}, nil
+}
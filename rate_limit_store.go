@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoRateLimitStore keeps buckets in a collection shared by all
+// replicas. Each request refills and takes from its bucket in a single
+// atomic update, and buckets are removed once they would be full again.
+type MongoRateLimitStore struct {
+	collection *mongo.Collection
+}
+
+func NewMongoRateLimitStore(collection *mongo.Collection) (*MongoRateLimitStore, error) {
+	_, err := collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &MongoRateLimitStore{
+		collection: collection,
+	}, nil
+}
+
+func (s *MongoRateLimitStore) Take(ctx context.Context, key string, limit RateLimit, now time.Time) (bool, time.Duration, error) {
+	burst := float64(limit.Burst)
+	refilled := bson.M{"$min": bson.A{burst, bson.M{"$add": bson.A{
+		bson.M{"$ifNull": bson.A{"$tokens", burst}},
+		bson.M{"$multiply": bson.A{
+			bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{now, bson.M{"$ifNull": bson.A{"$updatedAt", now}}}}, 1000}},
+			limit.Rate,
+		}},
+	}}}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"tokens": bson.M{"$max": bson.A{refilled, 0}}}}},
+		{{Key: "$set", Value: bson.M{
+			"allowed":   bson.M{"$gte": bson.A{"$tokens", 1}},
+			"updatedAt": now,
+			"expiresAt": now.Add(limit.refillTime()),
+		}}},
+		{{Key: "$set", Value: bson.M{"tokens": bson.M{"$cond": bson.A{"$allowed", bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}}}}},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var bucket struct {
+		Tokens  float64 `bson:"tokens"`
+		Allowed bool    `bson:"allowed"`
+	}
+	err := s.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&bucket)
+	if mongo.IsDuplicateKeyError(err) {
+		// A concurrent request created the bucket first
+		err = s.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&bucket)
+	}
+	if err != nil {
+		return false, 0, err
+	}
+	if !bucket.Allowed {
+		return false, limit.retryAfter(bucket.Tokens), nil
+	}
+	return true, 0, nil
+}
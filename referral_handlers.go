@@ -0,0 +1,130 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// @Summary Get a user's referral code
+// @Description Retrieves the code the user shares to invite others, creating it on first use
+// @Tags referrals
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} ReferralCode "Referral code"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 404 {object} ErrorResponse "Wallet not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /wallets/{id}/referral-code [get]
+func getReferralCode(c *gin.Context, repo *ReferralRepository, walletRepo *WalletRepository) {
+	ctx := c.Request.Context()
+	userId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := walletRepo.GetWalletByUserID(ctx, userId); err != nil {
+		if err == ErrWalletNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	code, err := repo.GetOrCreateCode(ctx, userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, code)
+}
+
+// @Summary Get a user's referrals
+// @Description Retrieves the users who registered with the user's referral code, newest first, with the bonuses paid
+// @Tags referrals
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {array} Referral "List of referrals"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /wallets/{id}/referrals [get]
+func getReferrals(c *gin.Context, repo *ReferralRepository) {
+	userId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	referrals, err := repo.GetReferralsByReferrer(c.Request.Context(), userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, referrals)
+}
+
+// @Summary Get referral program settings
+// @Description Retrieves the referral bonuses, the cap per referrer and the qualifying action types
+// @Tags referrals
+// @Accept json
+// @Produce json
+// @Success 200 {object} ReferralSettings "Referral program settings"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /referral-settings [get]
+func getReferralSettings(c *gin.Context, repo *ReferralRepository) {
+	settings, err := repo.GetSettings(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// @Summary Update referral program settings
+// @Description Sets the referral bonuses, the cap per referrer and the qualifying action types. Referrals completed earlier keep the bonuses they were paid.
+// @Tags referrals
+// @Accept json
+// @Produce json
+// @Param settings body ReferralSettings true "Referral program settings"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 200 {object} ReferralSettings "Referral program settings updated successfully"
+// @Failure 400 {object} ErrorResponse "Invalid settings"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /referral-settings [put]
+func updateReferralSettings(c *gin.Context, repo *ReferralRepository) {
+	existingSettings, err := repo.GetSettings(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var settings ReferralSettings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := settings.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if settings.QualifyingActionTypes == nil {
+		settings.QualifyingActionTypes = []string{}
+	}
+	settings.UpdatedAt = time.Now()
+
+	savedSettings, err := repo.SaveSettings(c.Request.Context(), &settings)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAuditChangeOf(c, "referral-settings", referralSettingsId, existingSettings, savedSettings)
+	c.JSON(http.StatusOK, savedSettings)
+}
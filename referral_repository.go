@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrReferralCodeNotFound = errors.New("referral code not found")
+var ErrReferralNotFound = errors.New("referral not found")
+var ErrSelfReferral = errors.New("users cannot refer themselves")
+var ErrInvalidReferralSettings = errors.New("invalid referral settings")
+
+const (
+	ReferralPending   = "pending"
+	ReferralCompleted = "completed"
+)
+
+const (
+	referralSettingsId          = "referrals"
+	defaultMaxRewardedReferrals = 20
+)
+
+// ReferralSettings configures the referral program. Both users get their
+// bonus when the referee completes a first qualifying action; an empty
+// QualifyingActionTypes accepts any action. A referrer is paid for at most
+// MaxRewardedReferrals referrals, defaultMaxRewardedReferrals unless set,
+// while their referees still get their bonus.
+type ReferralSettings struct {
+	Enabled               bool      `json:"enabled" bson:"enabled"`
+	ReferrerBonus         int       `json:"referrerBonus" bson:"referrerBonus"`
+	RefereeBonus          int       `json:"refereeBonus" bson:"refereeBonus"`
+	MaxRewardedReferrals  int       `json:"maxRewardedReferrals" bson:"maxRewardedReferrals"`
+	QualifyingActionTypes []string  `json:"qualifyingActionTypes" bson:"qualifyingActionTypes"`
+	UpdatedAt             time.Time `json:"updatedAt" bson:"updatedAt"`
+}
+
+func (s *ReferralSettings) Validate() error {
+	if s.ReferrerBonus < 0 || s.RefereeBonus < 0 {
+		return fmt.Errorf("%w: bonuses must not be negative", ErrInvalidReferralSettings)
+	}
+	if s.MaxRewardedReferrals <= 0 {
+		return fmt.Errorf("%w: maxRewardedReferrals must be positive", ErrInvalidReferralSettings)
+	}
+	for _, actionType := range s.QualifyingActionTypes {
+		if !slugPattern.MatchString(actionType) {
+			return fmt.Errorf("%w: invalid action type %q", ErrInvalidReferralSettings, actionType)
+		}
+	}
+	return nil
+}
+
+// ReferralCode is a user's personal code to share with people they invite.
+// RewardedReferrals counts the referrals the owner was paid a bonus for.
+type ReferralCode struct {
+	Code              string             `json:"code" bson:"_id"`
+	UserId            primitive.ObjectID `json:"userId" bson:"userId"`
+	RewardedReferrals int                `json:"rewardedReferrals" bson:"rewardedReferrals"`
+	CreatedAt         time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+// Referral links a new user to the user whose code they registered with.
+// ReferrerCapped is set when the referrer got no bonus because of the cap.
+type Referral struct {
+	Id                primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	ReferrerId        primitive.ObjectID `json:"referrerId" bson:"referrerId"`
+	RefereeId         primitive.ObjectID `json:"refereeId" bson:"refereeId"`
+	Code              string             `json:"code" bson:"code"`
+	Status            string             `json:"status" bson:"status"`
+	CreatedAt         time.Time          `json:"createdAt" bson:"createdAt"`
+	CompletedAt       *time.Time         `json:"completedAt,omitempty" bson:"completedAt,omitempty"`
+	QualifyingEventId string             `json:"qualifyingEventId,omitempty" bson:"qualifyingEventId,omitempty"`
+	ReferrerBonus     int                `json:"referrerBonus" bson:"referrerBonus"`
+	RefereeBonus      int                `json:"refereeBonus" bson:"refereeBonus"`
+	ReferrerCapped    bool               `json:"referrerCapped" bson:"referrerCapped"`
+}
+
+// generateReferralCode returns a random code such as "K7QD3XMA".
+func generateReferralCode() (string, error) {
+	raw := make([]byte, 5)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return voucherEncoding.EncodeToString(raw), nil
+}
+
+// normalizeReferralCode lets users type codes in any case.
+func normalizeReferralCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+type ReferralRepository struct {
+	codeCollection     *mongo.Collection
+	referralCollection *mongo.Collection
+	settingsCollection *mongo.Collection
+}
+
+func NewReferralRepository(codeCollection *mongo.Collection, referralCollection *mongo.Collection, settingsCollection *mongo.Collection) (*ReferralRepository, error) {
+	_, err := codeCollection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "userId", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	_, err = referralCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "refereeId", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "referrerId", Value: 1}, {Key: "createdAt", Value: -1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReferralRepository{
+		codeCollection:     codeCollection,
+		referralCollection: referralCollection,
+		settingsCollection: settingsCollection,
+	}, nil
+}
+
+// GetSettings returns the program settings, or a disabled program if none
+// were saved. Settings saved before the cap was required get the default cap.
+func (r *ReferralRepository) GetSettings(ctx context.Context) (*ReferralSettings, error) {
+	settings := ReferralSettings{QualifyingActionTypes: []string{}}
+	err := r.settingsCollection.FindOne(ctx, bson.M{"_id": referralSettingsId}).Decode(&settings)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	if settings.MaxRewardedReferrals <= 0 {
+		settings.MaxRewardedReferrals = defaultMaxRewardedReferrals
+	}
+	return &settings, nil
+}
+
+func (r *ReferralRepository) SaveSettings(ctx context.Context, settings *ReferralSettings) (*ReferralSettings, error) {
+	opts := options.Update().SetUpsert(true)
+	_, err := r.settingsCollection.UpdateOne(ctx, bson.M{"_id": referralSettingsId}, bson.M{"$set": settings}, opts)
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// GetOrCreateCode returns the user's referral code, creating one on first
+// use.
+func (r *ReferralRepository) GetOrCreateCode(ctx context.Context, userId primitive.ObjectID) (*ReferralCode, error) {
+	for range 5 {
+		var existing ReferralCode
+		err := r.codeCollection.FindOne(ctx, bson.M{"userId": userId}).Decode(&existing)
+		if err == nil {
+			return &existing, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+
+		code, err := generateReferralCode()
+		if err != nil {
+			return nil, err
+		}
+		referralCode := ReferralCode{Code: code, UserId: userId, CreatedAt: time.Now()}
+		_, err = r.codeCollection.InsertOne(ctx, referralCode)
+		if err == nil {
+			return &referralCode, nil
+		}
+		// Either the code is taken or a concurrent request created the
+		// user's code; look again.
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, err
+		}
+	}
+	return nil, errors.New("failed to generate a unique referral code")
+}
+
+func (r *ReferralRepository) GetCode(ctx context.Context, code string) (*ReferralCode, error) {
+	var referralCode ReferralCode
+	err := r.codeCollection.FindOne(ctx, bson.M{"_id": normalizeReferralCode(code)}).Decode(&referralCode)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrReferralCodeNotFound
+		}
+		return nil, err
+	}
+
+	return &referralCode, nil
+}
+
+// ClaimReferrerReward counts one more paid referral for the code's owner
+// unless that would exceed max. It reports whether the owner is to be paid.
+func (r *ReferralRepository) ClaimReferrerReward(ctx context.Context, code string, max int) (bool, error) {
+	filter := bson.M{"_id": code, "rewardedReferrals": bson.M{"$lt": max}}
+	result, err := r.codeCollection.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"rewardedReferrals": 1}})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+func (r *ReferralRepository) AddReferral(ctx context.Context, referral *Referral) (*Referral, error) {
+	result, err := r.referralCollection.InsertOne(ctx, referral)
+	if err != nil {
+		return nil, err
+	}
+	generatedID, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return nil, errors.New("failed to convert InsertedID to ObjectID")
+	}
+	referral.Id = generatedID
+	return referral, nil
+}
+
+// GetPendingReferral returns the referral the user registered with if it
+// has not been completed yet.
+func (r *ReferralRepository) GetPendingReferral(ctx context.Context, refereeId primitive.ObjectID) (*Referral, error) {
+	var referral Referral
+	err := r.referralCollection.FindOne(ctx, bson.M{"refereeId": refereeId, "status": ReferralPending}).Decode(&referral)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrReferralNotFound
+		}
+		return nil, err
+	}
+
+	return &referral, nil
+}
+
+func (r *ReferralRepository) GetReferralsByReferrer(ctx context.Context, referrerId primitive.ObjectID) ([]Referral, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cursor, err := r.referralCollection.Find(ctx, bson.M{"referrerId": referrerId}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	referrals := []Referral{}
+	if err := cursor.All(ctx, &referrals); err != nil {
+		return nil, err
+	}
+	return referrals, nil
+}
+
+// CompleteReferral stores the outcome of a pending referral.
+func (r *ReferralRepository) CompleteReferral(ctx context.Context, referral *Referral) error {
+	filter := bson.M{"_id": referral.Id, "status": ReferralPending}
+	result, err := r.referralCollection.ReplaceOne(ctx, filter, referral)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrReferralNotFound
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// completeReferral pays the referral bonuses inside the session's
+// transaction when the action is the first qualifying action of a referred
+// user. It returns the completed referral, or nil if nothing was paid.
+func completeReferral(sessCtx mongo.SessionContext, referralRepo *ReferralRepository, walletRepo *WalletRepository, leaderboardRepo *LeaderboardRepository, outbox *OutboxRepository, action *ActionEvent, now time.Time) (*Referral, error) {
+	settings, err := referralRepo.GetSettings(sessCtx)
+	if err != nil {
+		return nil, err
+	}
+	if !settings.Enabled {
+		return nil, nil
+	}
+	if len(settings.QualifyingActionTypes) > 0 && !slices.Contains(settings.QualifyingActionTypes, action.ActionType) {
+		return nil, nil
+	}
+	referral, err := referralRepo.GetPendingReferral(sessCtx, action.UserId)
+	if err != nil {
+		if err == ErrReferralNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// Step 1: Pay the referee
+	if settings.RefereeBonus > 0 {
+		wallet, err := walletRepo.GetWalletByUserID(sessCtx, referral.RefereeId)
+		if err != nil {
+			return nil, err
+		}
+		err = creditWallet(sessCtx, walletRepo, leaderboardRepo, outbox, wallet, TokensGrantedPayload{
+			Amount:     settings.RefereeBonus,
+			Source:     TokenSourceReferral,
+			ReferralId: &referral.Id,
+		}, now)
+		if err != nil {
+			return nil, err
+		}
+		referral.RefereeBonus = settings.RefereeBonus
+	}
+
+	// Step 2: Pay the referrer unless they reached the cap
+	if settings.ReferrerBonus > 0 {
+		claimed, err := referralRepo.ClaimReferrerReward(sessCtx, referral.Code, settings.MaxRewardedReferrals)
+		if err != nil {
+			return nil, err
+		}
+		if claimed {
+			wallet, err := walletRepo.GetWalletByUserID(sessCtx, referral.ReferrerId)
+			if err != nil {
+				return nil, err
+			}
+			err = creditWallet(sessCtx, walletRepo, leaderboardRepo, outbox, wallet, TokensGrantedPayload{
+				Amount:     settings.ReferrerBonus,
+				Source:     TokenSourceReferral,
+				ReferralId: &referral.Id,
+			}, now)
+			if err != nil {
+				return nil, err
+			}
+			referral.ReferrerBonus = settings.ReferrerBonus
+		} else {
+			referral.ReferrerCapped = true
+		}
+	}
+
+	// Step 3: Record the outcome and let other services know
+	referral.Status = ReferralCompleted
+	referral.CompletedAt = &now
+	referral.QualifyingEventId = action.EventId
+	if err := referralRepo.CompleteReferral(sessCtx, referral); err != nil {
+		return nil, err
+	}
+	event := NewEvent(EventReferralCompleted, referral.Id, ReferralCompletedPayload{
+		ReferralId:     referral.Id,
+		ReferrerId:     referral.ReferrerId,
+		RefereeId:      referral.RefereeId,
+		ReferrerBonus:  referral.ReferrerBonus,
+		RefereeBonus:   referral.RefereeBonus,
+		ReferrerCapped: referral.ReferrerCapped,
+		CompletedAt:    now,
+	})
+	if err := outbox.Add(sessCtx, event); err != nil {
+		return nil, err
+	}
+	return referral, nil
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	defaultReservationMinutes = 15
+	maxReservationMinutes     = 60
+)
+
+// @Summary Reserve a benefit
+// @Description Holds units of a benefit out of stock for a user for a number of minutes so they can be bought on a confirmation screen
+// @Tags reservations
+// @Accept json
+// @Produce json
+// @Param benefit_id path string true "Benefit ID"
+// @Param user_id body string true "User ID"
+// @Param quantity body int false "Units to hold, from 1 to 100, defaults to 1"
+// @Param minutes body int false "Minutes to hold the units for, defaults to 15, at most 60"
+// @Success 201 {object} Reservation "Reservation created"
+// @Failure 400 {object} ErrorResponse "Invalid request, benefit unavailable, out of stock or purchase limit reached"
+// @Failure 404 {object} ErrorResponse "Benefit or wallet not found"
+// @Failure 429 {object} ErrorResponse "Too many requests, see Retry-After"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /benefits/{benefit_id}/reserve [post]
+func reserveBenefit(c *gin.Context, reservationRepo *ReservationRepository, benefitRepo *BenefitRepository, walletRepo *WalletRepository, live *LiveUpdates) {
+	// Start a session
+	session, err := benefitRepo.client.StartSession()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start session"})
+		return
+	}
+	defer session.EndSession(c.Request.Context())
+
+	benefitId, err := primitive.ObjectIDFromHex(c.Param("benefit_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req struct {
+		UserID   primitive.ObjectID `json:"user_id"`
+		Quantity int                `json:"quantity"`
+		Minutes  int                `json:"minutes"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+	if req.Minutes == 0 {
+		req.Minutes = defaultReservationMinutes
+	}
+	if req.Quantity < 0 || req.Quantity > maxPurchaseQuantity {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("quantity must be between 1 and %d", maxPurchaseQuantity)})
+		return
+	}
+	if req.Minutes < 0 || req.Minutes > maxReservationMinutes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("minutes must be between 1 and %d", maxReservationMinutes)})
+		return
+	}
+
+	transaction := func(sessCtx mongo.SessionContext) (any, error) {
+		now := time.Now()
+
+		// Only users who could confirm the reservation may hold stock
+		if _, err := walletRepo.GetWalletByUserID(sessCtx, req.UserID); err != nil {
+			return nil, err
+		}
+
+		benefit, err := benefitRepo.GetBenefitByID(sessCtx, benefitId)
+		if err != nil {
+			return nil, err
+		}
+		if !benefit.IsAvailable(now) {
+			return nil, ErrBenefitUnavailable
+		}
+		allowance, err := benefitRepo.RemainingPurchases(sessCtx, benefit, req.UserID, now)
+		if err != nil {
+			return nil, err
+		}
+		if allowance.Limited {
+			// Units held by the user's other reservations count as bought
+			reserved, err := reservationRepo.ReservedQuantity(sessCtx, benefitId, req.UserID, now)
+			if err != nil {
+				return nil, err
+			}
+			if allowance.Remaining-reserved < req.Quantity {
+				return nil, ErrPurchaseLimitReached
+			}
+		}
+		if err := benefitRepo.DecrementStock(sessCtx, benefitId, req.Quantity); err != nil {
+			return nil, err
+		}
+
+		reservation := Reservation{
+			BenefitId: benefitId,
+			UserId:    req.UserID,
+			Quantity:  req.Quantity,
+			Status:    ReservationActive,
+			CreatedAt: now,
+			ExpiresAt: now.Add(time.Duration(req.Minutes) * time.Minute),
+		}
+		return reservationRepo.AddReservation(sessCtx, &reservation)
+	}
+
+	result, err := session.WithTransaction(c.Request.Context(), transaction)
+	if err != nil {
+		c.JSON(purchaseErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	live.StockChanged(c.Request.Context(), benefitId)
+	c.JSON(http.StatusCreated, result)
+}
+
+// @Summary Get a reservation
+// @Description Retrieves a reservation by its ID
+// @Tags reservations
+// @Accept json
+// @Produce json
+// @Param id path string true "Reservation ID"
+// @Success 200 {object} Reservation "Single reservation"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 404 {object} ErrorResponse "Reservation not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /reservations/{id} [get]
+func getReservation(c *gin.Context, repo *ReservationRepository) {
+	ctx := c.Request.Context()
+	reservationId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reservation, err := repo.GetReservationByID(ctx, reservationId)
+	if err != nil {
+		c.JSON(reservationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, reservation)
+}
+
+// @Summary Confirm a reservation
+// @Description Buys the units held by an active reservation through the regular purchase flow
+// @Tags reservations
+// @Accept json
+// @Produce json
+// @Param id path string true "Reservation ID"
+// @Param user_id body string true "User ID"
+// @Param promo_code body string false "Promo code"
+// @Success 200 {array} OwnedBenefit "Purchased benefits"
+// @Failure 400 {object} ErrorResponse "Invalid request, reservation expired, insufficient funds or purchase limit reached"
+// @Failure 404 {object} ErrorResponse "Reservation, benefit or wallet not found"
+// @Failure 409 {object} ErrorResponse "Reservation no longer active"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /reservations/{id}/confirm [post]
+func confirmReservation(c *gin.Context, reservationRepo *ReservationRepository, benefitRepo *BenefitRepository, walletRepo *WalletRepository, promoRepo *PromoCodeRepository, outbox *OutboxRepository, live *LiveUpdates) {
+	// Start a session
+	session, err := benefitRepo.client.StartSession()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start session"})
+		return
+	}
+	defer session.EndSession(c.Request.Context())
+
+	reservationId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req struct {
+		UserID    primitive.ObjectID `json:"user_id"`
+		PromoCode string             `json:"promo_code"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	transaction := func(sessCtx mongo.SessionContext) (any, error) {
+		reservation, err := reservationRepo.GetReservationByID(sessCtx, reservationId)
+		if err != nil {
+			return nil, err
+		}
+		if reservation.UserId != req.UserID {
+			return nil, ErrReservationNotFound
+		}
+		if reservation.Status != ReservationActive || !reservation.ExpiresAt.After(time.Now()) {
+			return nil, ErrReservationNotActive
+		}
+		if err := reservationRepo.CloseReservation(sessCtx, reservation.Id, ReservationConfirmed); err != nil {
+			return nil, err
+		}
+
+		return purchaseBenefit(sessCtx, benefitRepo, walletRepo, promoRepo, outbox, Purchase{
+			BenefitId: reservation.BenefitId,
+			UserId:    reservation.UserId,
+			Quantity:  reservation.Quantity,
+			PromoCode: req.PromoCode,
+			StockHeld: true,
+		})
+	}
+
+	result, err := session.WithTransaction(c.Request.Context(), transaction)
+	if err != nil {
+		c.JSON(reservationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	// The units left stock when they were reserved, so only the wallet changed
+	live.WalletChanged(c.Request.Context(), req.UserID)
+	c.JSON(http.StatusOK, result)
+}
+
+// @Summary Cancel a reservation
+// @Description Releases the units held by an active reservation back into stock
+// @Tags reservations
+// @Accept json
+// @Produce json
+// @Param id path string true "Reservation ID"
+// @Param user_id body string true "User ID"
+// @Success 200 {object} Reservation "Released reservation"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 404 {object} ErrorResponse "Reservation not found"
+// @Failure 409 {object} ErrorResponse "Reservation no longer active"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /reservations/{id}/cancel [post]
+func cancelReservation(c *gin.Context, reservationRepo *ReservationRepository, benefitRepo *BenefitRepository, live *LiveUpdates) {
+	ctx := c.Request.Context()
+	reservationId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req struct {
+		UserID primitive.ObjectID `json:"user_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reservation, err := reservationRepo.GetReservationByID(ctx, reservationId)
+	if err == nil && reservation.UserId != req.UserID {
+		err = ErrReservationNotFound
+	}
+	if err == nil {
+		err = releaseReservation(ctx, reservationRepo, benefitRepo, reservation)
+	}
+	if err != nil {
+		c.JSON(reservationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	live.StockChanged(ctx, reservation.BenefitId)
+	c.JSON(http.StatusOK, reservation)
+}
+
+func reservationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrReservationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrReservationNotActive):
+		return http.StatusConflict
+	default:
+		return purchaseErrorStatus(err)
+	}
+}
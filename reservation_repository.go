@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	ReservationActive    = "active"
+	ReservationConfirmed = "confirmed"
+	ReservationReleased  = "released"
+)
+
+var ErrReservationNotFound = errors.New("reservation not found")
+var ErrReservationNotActive = errors.New("reservation is no longer active")
+
+// Reservation holds units of a benefit out of stock for a user until it is
+// confirmed, cancelled or expires.
+type Reservation struct {
+	Id        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	BenefitId primitive.ObjectID `json:"benefitId" bson:"benefitId"`
+	UserId    primitive.ObjectID `json:"userId" bson:"userId"`
+	Quantity  int                `json:"quantity" bson:"quantity"`
+	Status    string             `json:"status" bson:"status" enums:"active,confirmed,released"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+	ExpiresAt time.Time          `json:"expiresAt" bson:"expiresAt"`
+}
+
+type ReservationRepository struct {
+	collection *mongo.Collection
+}
+
+func NewReservationRepository(collection *mongo.Collection) (*ReservationRepository, error) {
+	return &ReservationRepository{
+		collection: collection,
+	}, nil
+}
+
+func (r *ReservationRepository) GetReservationByID(ctx context.Context, id primitive.ObjectID) (*Reservation, error) {
+	var reservation Reservation
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&reservation)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrReservationNotFound
+		}
+		return nil, err
+	}
+
+	return &reservation, nil
+}
+
+func (r *ReservationRepository) AddReservation(ctx context.Context, reservation *Reservation) (*Reservation, error) {
+	result, err := r.collection.InsertOne(ctx, reservation)
+	if err != nil {
+		return nil, err
+	}
+	generatedID, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return nil, errors.New("failed to convert InsertedID to ObjectID")
+	}
+	reservation.Id = generatedID
+	return reservation, nil
+}
+
+// ReservedQuantity sums the units of the benefit the user holds in active
+// reservations that have not run out by now.
+func (r *ReservationRepository) ReservedQuantity(ctx context.Context, benefitId primitive.ObjectID, userId primitive.ObjectID, now time.Time) (int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"benefitId": benefitId,
+			"userId":    userId,
+			"status":    ReservationActive,
+			"expiresAt": bson.M{"$gt": now},
+		}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "quantity": bson.M{"$sum": "$quantity"}}}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var result struct {
+		Quantity int `bson:"quantity"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&result); err != nil {
+			return 0, err
+		}
+	}
+	return result.Quantity, cursor.Err()
+}
+
+// GetExpiredReservations returns active reservations whose hold ran out at
+// or before now.
+func (r *ReservationRepository) GetExpiredReservations(ctx context.Context, now time.Time) ([]Reservation, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"status": ReservationActive, "expiresAt": bson.M{"$lte": now}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var reservations []Reservation
+	for cursor.Next(ctx) {
+		var reservation Reservation
+		err := cursor.Decode(&reservation)
+		if err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, reservation)
+	}
+
+	return reservations, nil
+}
+
+// CloseReservation moves an active reservation to the given status. It
+// fails with ErrReservationNotActive if the reservation was closed already.
+func (r *ReservationRepository) CloseReservation(ctx context.Context, id primitive.ObjectID, status string) error {
+	filter := bson.M{"_id": id, "status": ReservationActive}
+	update := bson.M{"$set": bson.M{"status": status}}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrReservationNotActive
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ReservationSweeper periodically releases expired reservations and puts
+// the held units back into stock.
+type ReservationSweeper struct {
+	reservationRepo *ReservationRepository
+	benefitRepo     *BenefitRepository
+	live            *LiveUpdates
+	interval        time.Duration
+}
+
+func NewReservationSweeper(reservationRepo *ReservationRepository, benefitRepo *BenefitRepository, live *LiveUpdates, interval time.Duration) *ReservationSweeper {
+	return &ReservationSweeper{
+		reservationRepo: reservationRepo,
+		benefitRepo:     benefitRepo,
+		live:            live,
+		interval:        interval,
+	}
+}
+
+// Run sweeps once immediately and then on every tick until ctx is cancelled.
+func (s *ReservationSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Sweep(ctx, time.Now()); err != nil {
+			log.Printf("reservation sweep failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *ReservationSweeper) Sweep(ctx context.Context, now time.Time) error {
+	reservations, err := s.reservationRepo.GetExpiredReservations(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for _, reservation := range reservations {
+		err := releaseReservation(ctx, s.reservationRepo, s.benefitRepo, &reservation)
+		if err != nil {
+			if err != ErrReservationNotActive {
+				log.Printf("failed to release reservation %s: %v", reservation.Id.Hex(), err)
+			}
+			continue
+		}
+		s.live.StockChanged(ctx, reservation.BenefitId)
+	}
+
+	return nil
+}
+
+// releaseReservation closes an active reservation and returns its units to
+// stock in one transaction.
+func releaseReservation(ctx context.Context, reservationRepo *ReservationRepository, benefitRepo *BenefitRepository, reservation *Reservation) error {
+	session, err := benefitRepo.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	transaction := func(sessCtx mongo.SessionContext) (any, error) {
+		if err := reservationRepo.CloseReservation(sessCtx, reservation.Id, ReservationReleased); err != nil {
+			return nil, err
+		}
+		if err := benefitRepo.IncrementStock(sessCtx, reservation.BenefitId, reservation.Quantity); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	_, err = session.WithTransaction(ctx, transaction)
+	if err != nil {
+		return err
+	}
+	reservation.Status = ReservationReleased
+	return nil
+}
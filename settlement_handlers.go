@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const settlementDateLayout = "2006-01-02"
+
+// parsePeriod reads a period from dates in YYYY-MM-DD or RFC 3339 format.
+// Dates start at midnight in the city's time zone.
+func parsePeriod(from string, to string) (time.Time, time.Time, error) {
+	parse := func(name string, value string) (time.Time, error) {
+		if t, err := time.ParseInLocation(settlementDateLayout, value, cityLocation); err == nil {
+			return t, nil
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("Invalid %s", name)
+		}
+		return t, nil
+	}
+
+	start, err := parse("from", from)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	end, err := parse("to", to)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if !end.After(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("to must be after from")
+	}
+	return start.UTC(), end.UTC(), nil
+}
+
+// buildSettlementReport returns the frozen report if the period was settled
+// and a freshly aggregated one otherwise.
+func buildSettlementReport(c *gin.Context, repo *SettlementRepository, benefitRepo *BenefitRepository, partnerId primitive.ObjectID, from time.Time, to time.Time, tokenRate float64) (*SettlementReport, error) {
+	ctx := c.Request.Context()
+	settlement, err := repo.GetSettlement(ctx, partnerId, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if settlement != nil {
+		return &settlement.SettlementReport, nil
+	}
+
+	report, err := repo.BuildReport(ctx, partnerId, from, to, tokenRate)
+	if err != nil {
+		return nil, err
+	}
+	for i := range report.Lines {
+		benefit, err := benefitRepo.GetBenefitByID(ctx, report.Lines[i].BenefitId)
+		if err == nil {
+			report.Lines[i].BenefitName = benefit.Name
+		}
+	}
+	return report, nil
+}
+
+// @Summary Get a settlement report
+// @Description Aggregates purchases and redemptions of a partner's benefits in a period, with token totals converted to money at the configured rate. Settled periods return the frozen report.
+// @Tags settlements
+// @Accept json
+// @Produce json,text/csv
+// @Param id path string true "Partner ID"
+// @Param from query string true "Start of the period (inclusive), YYYY-MM-DD in the city's time zone or RFC 3339"
+// @Param to query string true "End of the period (exclusive), YYYY-MM-DD in the city's time zone or RFC 3339"
+// @Param format query string false "json (default) or csv"
+// @Success 200 {object} SettlementReport "Settlement report"
+// @Failure 400 {object} ErrorResponse "Invalid ID format or period"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /partners/{id}/settlements/report [get]
+func getSettlementReport(c *gin.Context, repo *SettlementRepository, benefitRepo *BenefitRepository, tokenRate float64) {
+	partnerId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	from, to, err := parsePeriod(c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := buildSettlementReport(c, repo, benefitRepo, partnerId, from, to, tokenRate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.JSON(http.StatusOK, report)
+	case "csv":
+		writeSettlementCSV(c, report)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format"})
+	}
+}
+
+func writeSettlementCSV(c *gin.Context, report *SettlementReport) {
+	filename := fmt.Sprintf("settlement-%s-%s-%s.csv", report.PartnerId.Hex(), report.PeriodStart.In(cityLocation).Format(settlementDateLayout), report.PeriodEnd.In(cityLocation).Format(settlementDateLayout))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Content-Type", "text/csv")
+	c.Status(http.StatusOK)
+
+	itoa := strconv.Itoa
+	money := func(value float64) string {
+		return strconv.FormatFloat(value, 'f', 2, 64)
+	}
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"benefit_id", "benefit_name", "purchase_count", "purchase_tokens", "redemption_count", "redemption_tokens"})
+	for _, line := range report.Lines {
+		w.Write([]string{line.BenefitId.Hex(), line.BenefitName, itoa(line.PurchaseCount), itoa(line.PurchaseTokens), itoa(line.RedemptionCount), itoa(line.RedemptionTokens)})
+	}
+	w.Write([]string{"total", "", itoa(report.PurchaseCount), itoa(report.PurchaseTokens), itoa(report.RedemptionCount), itoa(report.RedemptionTokens)})
+	w.Write([]string{"value", money(report.TokenRate), "", money(report.PurchaseValue), "", money(report.RedemptionValue)})
+	w.Flush()
+}
+
+// @Summary Get settlements
+// @Description Lists the settled periods of a partner
+// @Tags settlements
+// @Accept json
+// @Produce json
+// @Param id path string true "Partner ID"
+// @Success 200 {array} Settlement "List of settlements"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /partners/{id}/settlements [get]
+func getSettlements(c *gin.Context, repo *SettlementRepository) {
+	ctx := c.Request.Context()
+	partnerId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settlements, err := repo.GetSettlements(ctx, partnerId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, settlements)
+}
+
+// @Summary Settle a period
+// @Description Freezes the settlement report of a partner for a period so it can no longer change
+// @Tags settlements
+// @Accept json
+// @Produce json
+// @Param id path string true "Partner ID"
+// @Param from body string true "Start of the period (inclusive), YYYY-MM-DD in the city's time zone or RFC 3339"
+// @Param to body string true "End of the period (exclusive), YYYY-MM-DD in the city's time zone or RFC 3339"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 201 {object} Settlement "Settlement created"
+// @Failure 400 {object} ErrorResponse "Invalid ID format or period"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 404 {object} ErrorResponse "Partner not found"
+// @Failure 409 {object} ErrorResponse "Period overlaps a settled period"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /partners/{id}/settlements [post]
+func settlePeriod(c *gin.Context, repo *SettlementRepository, partnerRepo *PartnerRepository, benefitRepo *BenefitRepository, tokenRate float64) {
+	ctx := c.Request.Context()
+	partnerId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	from, to, err := parsePeriod(req.From, req.To)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if to.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "only past periods can be settled"})
+		return
+	}
+
+	if _, err := partnerRepo.GetPartnerByID(ctx, partnerId); err != nil {
+		if err == ErrPartnerNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := buildSettlementReport(c, repo, benefitRepo, partnerId, from, to, tokenRate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := repo.collection.Database().Client().StartSession()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer session.EndSession(ctx)
+
+	transaction := func(sessCtx mongo.SessionContext) (any, error) {
+		if err := repo.LockPartner(sessCtx, partnerId, time.Now()); err != nil {
+			return nil, err
+		}
+		return repo.AddSettlement(sessCtx, report)
+	}
+
+	settlement, err := session.WithTransaction(ctx, transaction)
+	if err != nil {
+		if err == ErrSettlementOverlaps {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, settlement)
+}
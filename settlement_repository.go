@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrSettlementOverlaps = errors.New("period overlaps an already settled period")
+
+// SettlementLine aggregates one benefit's purchases and redemptions.
+type SettlementLine struct {
+	BenefitId        primitive.ObjectID `json:"benefitId" bson:"benefitId"`
+	BenefitName      string             `json:"benefitName" bson:"benefitName"`
+	PurchaseCount    int                `json:"purchaseCount" bson:"purchaseCount"`
+	PurchaseTokens   int                `json:"purchaseTokens" bson:"purchaseTokens"`
+	RedemptionCount  int                `json:"redemptionCount" bson:"redemptionCount"`
+	RedemptionTokens int                `json:"redemptionTokens" bson:"redemptionTokens"`
+}
+
+// SettlementReport sums up what the city owes a partner for a period. The
+// period includes PeriodStart and excludes PeriodEnd.
+type SettlementReport struct {
+	PartnerId        primitive.ObjectID `json:"partnerId" bson:"partnerId"`
+	PeriodStart      time.Time          `json:"periodStart" bson:"periodStart"`
+	PeriodEnd        time.Time          `json:"periodEnd" bson:"periodEnd"`
+	Lines            []SettlementLine   `json:"lines" bson:"lines"`
+	PurchaseCount    int                `json:"purchaseCount" bson:"purchaseCount"`
+	PurchaseTokens   int                `json:"purchaseTokens" bson:"purchaseTokens"`
+	RedemptionCount  int                `json:"redemptionCount" bson:"redemptionCount"`
+	RedemptionTokens int                `json:"redemptionTokens" bson:"redemptionTokens"`
+	TokenRate        float64            `json:"tokenRate" bson:"tokenRate"`
+	PurchaseValue    float64            `json:"purchaseValue" bson:"purchaseValue"`
+	RedemptionValue  float64            `json:"redemptionValue" bson:"redemptionValue"`
+	Settled          bool               `json:"settled" bson:"settled"`
+	SettledAt        *time.Time         `json:"settledAt,omitempty" bson:"settledAt,omitempty"`
+}
+
+// Settlement is a report frozen when its period was marked as settled.
+type Settlement struct {
+	Id               primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	SettlementReport `bson:",inline"`
+}
+
+type SettlementRepository struct {
+	collection             *mongo.Collection
+	ownedBenefitCollection *mongo.Collection
+	lockCollection         *mongo.Collection
+}
+
+func NewSettlementRepository(collection *mongo.Collection, ownedBenefitCollection *mongo.Collection, lockCollection *mongo.Collection) (*SettlementRepository, error) {
+	_, err := collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "partnerId", Value: 1}, {Key: "periodStart", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &SettlementRepository{
+		collection:             collection,
+		ownedBenefitCollection: ownedBenefitCollection,
+		lockCollection:         lockCollection,
+	}, nil
+}
+
+type settlementTotals struct {
+	BenefitId primitive.ObjectID `bson:"_id"`
+	Count     int                `bson:"count"`
+	Tokens    int                `bson:"tokens"`
+}
+
+// aggregateByBenefit counts the partner's owned benefits whose dateField
+// falls into the period and sums what was paid for them.
+func (r *SettlementRepository) aggregateByBenefit(ctx context.Context, partnerId primitive.ObjectID, dateField string, from time.Time, to time.Time) ([]settlementTotals, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"partnerId": partnerId,
+			dateField:   bson.M{"$gte": from, "$lt": to},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    "$benefitId",
+			"count":  bson.M{"$sum": 1},
+			"tokens": bson.M{"$sum": "$pricePaid"},
+		}}},
+	}
+	cursor, err := r.ownedBenefitCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var totals []settlementTotals
+	if err := cursor.All(ctx, &totals); err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+// BuildReport aggregates purchases and redemptions of the partner's
+// benefits in the period. Benefit names are not filled in.
+func (r *SettlementRepository) BuildReport(ctx context.Context, partnerId primitive.ObjectID, from time.Time, to time.Time, tokenRate float64) (*SettlementReport, error) {
+	purchases, err := r.aggregateByBenefit(ctx, partnerId, "purchased", from, to)
+	if err != nil {
+		return nil, err
+	}
+	redemptions, err := r.aggregateByBenefit(ctx, partnerId, "redeemedAt", from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SettlementReport{
+		PartnerId:   partnerId,
+		PeriodStart: from,
+		PeriodEnd:   to,
+		Lines:       []SettlementLine{},
+		TokenRate:   tokenRate,
+	}
+	lines := map[primitive.ObjectID]*SettlementLine{}
+	var order []primitive.ObjectID
+	line := func(benefitId primitive.ObjectID) *SettlementLine {
+		if lines[benefitId] == nil {
+			lines[benefitId] = &SettlementLine{BenefitId: benefitId}
+			order = append(order, benefitId)
+		}
+		return lines[benefitId]
+	}
+	for _, total := range purchases {
+		l := line(total.BenefitId)
+		l.PurchaseCount = total.Count
+		l.PurchaseTokens = total.Tokens
+	}
+	for _, total := range redemptions {
+		l := line(total.BenefitId)
+		l.RedemptionCount = total.Count
+		l.RedemptionTokens = total.Tokens
+	}
+	for _, benefitId := range order {
+		report.Lines = append(report.Lines, *lines[benefitId])
+	}
+
+	for _, l := range report.Lines {
+		report.PurchaseCount += l.PurchaseCount
+		report.PurchaseTokens += l.PurchaseTokens
+		report.RedemptionCount += l.RedemptionCount
+		report.RedemptionTokens += l.RedemptionTokens
+	}
+	report.PurchaseValue = float64(report.PurchaseTokens) * tokenRate
+	report.RedemptionValue = float64(report.RedemptionTokens) * tokenRate
+
+	return report, nil
+}
+
+// GetSettlement returns the settlement for exactly this period, or nil if
+// the period has not been settled.
+func (r *SettlementRepository) GetSettlement(ctx context.Context, partnerId primitive.ObjectID, from time.Time, to time.Time) (*Settlement, error) {
+	var settlement Settlement
+	filter := bson.M{"partnerId": partnerId, "periodStart": from, "periodEnd": to}
+	err := r.collection.FindOne(ctx, filter).Decode(&settlement)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &settlement, nil
+}
+
+func (r *SettlementRepository) GetSettlements(ctx context.Context, partnerId primitive.ObjectID) ([]Settlement, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "periodStart", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"partnerId": partnerId}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var settlements []Settlement
+	for cursor.Next(ctx) {
+		var settlement Settlement
+		err := cursor.Decode(&settlement)
+		if err != nil {
+			return nil, err
+		}
+		settlements = append(settlements, settlement)
+	}
+
+	return settlements, nil
+}
+
+// LockPartner writes the partner's settlement lock document. Inside a
+// transaction this makes concurrent settlements of the same partner
+// conflict, so only one of them passes the overlap check in AddSettlement.
+func (r *SettlementRepository) LockPartner(ctx context.Context, partnerId primitive.ObjectID, now time.Time) error {
+	update := bson.M{"$set": bson.M{"lockedAt": now}}
+	_, err := r.lockCollection.UpdateOne(ctx, bson.M{"_id": partnerId}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// AddSettlement stores the report as settled. It fails with
+// ErrSettlementOverlaps if any part of the period was settled before. It
+// must run in a transaction after LockPartner.
+func (r *SettlementRepository) AddSettlement(ctx context.Context, report *SettlementReport) (*Settlement, error) {
+	overlap := bson.M{
+		"partnerId":   report.PartnerId,
+		"periodStart": bson.M{"$lt": report.PeriodEnd},
+		"periodEnd":   bson.M{"$gt": report.PeriodStart},
+	}
+	count, err := r.collection.CountDocuments(ctx, overlap)
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrSettlementOverlaps
+	}
+
+	now := time.Now()
+	report.Settled = true
+	report.SettledAt = &now
+	settlement := Settlement{SettlementReport: *report}
+	result, err := r.collection.InsertOne(ctx, settlement)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrSettlementOverlaps
+		}
+		return nil, err
+	}
+	generatedID, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return nil, errors.New("failed to convert InsertedID to ObjectID")
+	}
+	settlement.Id = generatedID
+	return &settlement, nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Granted tokens expire this many months after the grant.
+const tokenLifetimeMonths = 12
+
+func tokenExpiryFrom(grantedAt time.Time) time.Time {
+	return grantedAt.AddDate(0, tokenLifetimeMonths, 0)
+}
+
+// TokenExpirySweeper periodically removes expired token lots from wallets
+// and records every expiry.
+type TokenExpirySweeper struct {
+	walletRepo *WalletRepository
+	outbox     *OutboxRepository
+	live       *LiveUpdates
+	interval   time.Duration
+}
+
+func NewTokenExpirySweeper(walletRepo *WalletRepository, outbox *OutboxRepository, live *LiveUpdates, interval time.Duration) *TokenExpirySweeper {
+	return &TokenExpirySweeper{
+		walletRepo: walletRepo,
+		outbox:     outbox,
+		live:       live,
+		interval:   interval,
+	}
+}
+
+// Run sweeps once immediately and then on every tick until ctx is cancelled.
+func (s *TokenExpirySweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Sweep(ctx, time.Now()); err != nil {
+			log.Printf("token expiry sweep failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *TokenExpirySweeper) Sweep(ctx context.Context, now time.Time) error {
+	wallets, err := s.walletRepo.GetWalletsWithExpiredLots(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for _, wallet := range wallets {
+		if err := s.expireWallet(ctx, wallet, now); err != nil {
+			log.Printf("failed to expire tokens for user %s: %v", wallet.UserId.Hex(), err)
+			continue
+		}
+		s.live.WalletChanged(ctx, wallet.UserId)
+	}
+
+	return nil
+}
+
+func (s *TokenExpirySweeper) expireWallet(ctx context.Context, wallet Wallet, now time.Time) error {
+	session, err := s.walletRepo.collection.Database().Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	transaction := func(sessCtx mongo.SessionContext) (any, error) {
+		// Reload the wallet so purchases made since the query are respected
+		current, err := s.walletRepo.GetWalletByUserID(sessCtx, wallet.UserId)
+		if err != nil {
+			return nil, err
+		}
+
+		expired := current.ExpireLots(now)
+		if len(expired) == 0 {
+			return nil, nil
+		}
+		if _, err := s.walletRepo.UpdateWallet(sessCtx, current); err != nil {
+			return nil, err
+		}
+
+		for _, lot := range expired {
+			if lot.Remaining == 0 {
+				continue
+			}
+			expiration := TokenExpiration{
+				UserId:    current.UserId,
+				LotId:     lot.Id,
+				Amount:    lot.Remaining,
+				ExpiredAt: now,
+			}
+			if _, err := s.walletRepo.AddTokenExpiration(sessCtx, &expiration); err != nil {
+				return nil, err
+			}
+			event := NewEvent(EventTokensExpired, current.Id, TokensExpiredPayload{
+				UserId:    current.UserId,
+				LotId:     lot.Id,
+				Amount:    lot.Remaining,
+				ExpiredAt: now,
+			})
+			if err := s.outbox.Add(sessCtx, event); err != nil {
+				return nil, err
+			}
+		}
+
+		return nil, nil
+	}
+
+	_, err = session.WithTransaction(ctx, transaction)
+	return err
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	TokenSourceManual    = "manual"
+	TokenSourceAction    = "action"
+	TokenSourceChallenge = "challenge"
+	TokenSourceReferral  = "referral"
+)
+
+// creditWallet grants grant.Amount tokens to the wallet inside the
+// session's transaction as a new lot, counts them on the leaderboards and
+// adds a TokensGranted event to the outbox. The caller says where the
+// tokens came from in grant; the user and expiry are filled in.
+func creditWallet(sessCtx mongo.SessionContext, walletRepo *WalletRepository, leaderboardRepo *LeaderboardRepository, outbox *OutboxRepository, wallet *Wallet, grant TokensGrantedPayload, now time.Time) error {
+	grant.UserId = wallet.UserId
+	grant.ExpiresAt = tokenExpiryFrom(now)
+	if err := wallet.CreditTokens(grant.Amount, now, grant.ExpiresAt); err != nil {
+		return err
+	}
+	if _, err := walletRepo.UpdateWallet(sessCtx, wallet); err != nil {
+		return err
+	}
+	if err := leaderboardRepo.AddGrant(sessCtx, wallet.UserId, grant.Amount, now); err != nil {
+		return err
+	}
+	return outbox.Add(sessCtx, NewEvent(EventTokensGranted, wallet.Id, grant))
+}
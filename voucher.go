@@ -0,0 +1,27 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"regexp"
+	"strings"
+)
+
+// Base32 alphabet without the easily confused 0/O and 1/I
+var voucherEncoding = base32.NewEncoding("ABCDEFGHJKLMNPQRSTUVWXYZ23456789").WithPadding(base32.NoPadding)
+
+// voucherCodePattern matches codes made by generateVoucherCode. Owned
+// benefits bought before voucher codes existed hold placeholder content
+// that must never be redeemable.
+var voucherCodePattern = regexp.MustCompile(`^[A-HJ-NP-Z2-9]{4}-[A-HJ-NP-Z2-9]{4}-[A-HJ-NP-Z2-9]{4}$`)
+
+// generateVoucherCode returns a random code such as "K7QD-3XMA-PZ9H" that is
+// handed to the partner when the benefit is redeemed.
+func generateVoucherCode() (string, error) {
+	raw := make([]byte, 8)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	encoded := voucherEncoding.EncodeToString(raw)[:12]
+	return strings.Join([]string{encoded[0:4], encoded[4:8], encoded[8:12]}, "-"), nil
+}
@@ -3,28 +3,174 @@ package main
 import (
 	"context"
 	"errors"
+	"sort"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 var ErrWalletNotFound = errors.New("wallet not found")
+var ErrInsufficientFunds = errors.New("insufficient funds")
+var ErrWalletExists = errors.New("wallet already exists")
+var ErrInvalidTokenAmount = errors.New("token amount must be positive")
+
+// TokenLot is a dated batch of granted tokens. Lots are spent oldest first
+// and whatever remains in a lot is removed once it expires.
+type TokenLot struct {
+	Id        primitive.ObjectID `json:"id" bson:"_id"`
+	Amount    int                `json:"amount" bson:"amount"`
+	Remaining int                `json:"remaining" bson:"remaining"`
+	GrantedAt time.Time          `json:"granted_at" bson:"granted_at"`
+	ExpiresAt time.Time          `json:"expires_at" bson:"expires_at"`
+}
+
+// UpcomingExpiration is a summary of tokens that will expire at a given time.
+type UpcomingExpiration struct {
+	Amount    int       `json:"amount"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// TokenExpiration records tokens removed from a wallet by the expiry sweeper.
+type TokenExpiration struct {
+	Id        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserId    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	LotId     primitive.ObjectID `json:"lot_id" bson:"lot_id"`
+	Amount    int                `json:"amount" bson:"amount"`
+	ExpiredAt time.Time          `json:"expired_at" bson:"expired_at"`
+}
 
 type Wallet struct {
-	Id           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	UserId       primitive.ObjectID `json:"user_id" bson:"user_id"`
-	MoneyBalance float64            `json:"money_balance" bson:"money_balance"`
-	TokenBalance int            `json:"token_balance" bson:"token_balance"`
+	Id                  primitive.ObjectID   `json:"id" bson:"_id,omitempty"`
+	UserId              primitive.ObjectID   `json:"user_id" bson:"user_id"`
+	MoneyBalance        float64              `json:"money_balance" bson:"money_balance"`
+	TokenBalance        int                  `json:"token_balance" bson:"token_balance"`
+	TokenLots           []TokenLot           `json:"token_lots" bson:"token_lots"`
+	UpcomingExpirations []UpcomingExpiration `json:"upcoming_expirations,omitempty" bson:"-"`
+}
+
+// CreditTokens adds a new token lot to the wallet. It fails with
+// ErrInvalidTokenAmount unless amount is positive.
+func (w *Wallet) CreditTokens(amount int, grantedAt time.Time, expiresAt time.Time) error {
+	if amount <= 0 {
+		return ErrInvalidTokenAmount
+	}
+	w.TokenLots = append(w.TokenLots, TokenLot{
+		Id:        primitive.NewObjectID(),
+		Amount:    amount,
+		Remaining: amount,
+		GrantedAt: grantedAt,
+		ExpiresAt: expiresAt,
+	})
+	w.TokenBalance += amount
+	return nil
+}
+
+// DebitTokens spends tokens from the oldest lots first. Tokens that were
+// granted before lots existed are not tracked by any lot and are spent
+// before all others.
+func (w *Wallet) DebitTokens(amount int) error {
+	if w.TokenBalance < amount {
+		return ErrInsufficientFunds
+	}
+
+	sort.SliceStable(w.TokenLots, func(i, j int) bool {
+		return w.TokenLots[i].GrantedAt.Before(w.TokenLots[j].GrantedAt)
+	})
+
+	remaining := amount
+	untracked := w.TokenBalance - w.lotsBalance()
+	if untracked > 0 {
+		remaining -= min(untracked, remaining)
+	}
+	for i := range w.TokenLots {
+		if remaining == 0 {
+			break
+		}
+		spent := min(w.TokenLots[i].Remaining, remaining)
+		w.TokenLots[i].Remaining -= spent
+		remaining -= spent
+	}
+	w.TokenBalance -= amount
+	w.dropSpentLots()
+
+	return nil
+}
+
+// ExpireLots removes lots that expired at or before now and returns them
+// with the amount that was still left in each.
+func (w *Wallet) ExpireLots(now time.Time) []TokenLot {
+	var expired []TokenLot
+	var kept []TokenLot
+	for _, lot := range w.TokenLots {
+		if !lot.ExpiresAt.After(now) {
+			expired = append(expired, lot)
+			w.TokenBalance -= lot.Remaining
+			continue
+		}
+		kept = append(kept, lot)
+	}
+	w.TokenLots = kept
+
+	return expired
+}
+
+// WithUpcomingExpirations fills UpcomingExpirations from the wallet's lots.
+func (w *Wallet) WithUpcomingExpirations() *Wallet {
+	w.UpcomingExpirations = nil
+	for _, lot := range w.TokenLots {
+		if lot.Remaining == 0 {
+			continue
+		}
+		w.UpcomingExpirations = append(w.UpcomingExpirations, UpcomingExpiration{
+			Amount:    lot.Remaining,
+			ExpiresAt: lot.ExpiresAt,
+		})
+	}
+	sort.Slice(w.UpcomingExpirations, func(i, j int) bool {
+		return w.UpcomingExpirations[i].ExpiresAt.Before(w.UpcomingExpirations[j].ExpiresAt)
+	})
+
+	return w
+}
+
+func (w *Wallet) lotsBalance() int {
+	total := 0
+	for _, lot := range w.TokenLots {
+		total += lot.Remaining
+	}
+	return total
+}
+
+func (w *Wallet) dropSpentLots() {
+	kept := w.TokenLots[:0]
+	for _, lot := range w.TokenLots {
+		if lot.Remaining > 0 {
+			kept = append(kept, lot)
+		}
+	}
+	w.TokenLots = kept
 }
 
 type WalletRepository struct {
-	collection *mongo.Collection
+	collection           *mongo.Collection
+	expirationCollection *mongo.Collection
 }
 
-func NewWalletRepository(collection *mongo.Collection) (*WalletRepository, error) {
+func NewWalletRepository(collection *mongo.Collection, expirationCollection *mongo.Collection) (*WalletRepository, error) {
+	_, err := collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	return &WalletRepository{
-		collection: collection,
+		collection:           collection,
+		expirationCollection: expirationCollection,
 	}, nil
 }
 
@@ -42,7 +188,17 @@ func (r *WalletRepository) GetWalletByUserID(ctx context.Context, id primitive.O
 }
 
 func (r *WalletRepository) GetAllWallets(ctx context.Context) ([]Wallet, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{})
+	return r.getWallets(ctx, bson.M{})
+}
+
+// GetWalletsWithExpiredLots returns wallets holding at least one lot that
+// expired at or before now.
+func (r *WalletRepository) GetWalletsWithExpiredLots(ctx context.Context, now time.Time) ([]Wallet, error) {
+	return r.getWallets(ctx, bson.M{"token_lots.expires_at": bson.M{"$lte": now}})
+}
+
+func (r *WalletRepository) getWallets(ctx context.Context, filter bson.M) ([]Wallet, error) {
+	cursor, err := r.collection.Find(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -61,6 +217,24 @@ func (r *WalletRepository) GetAllWallets(ctx context.Context) ([]Wallet, error)
 	return wallets, nil
 }
 
+// AddWallet creates the user's wallet. It fails with ErrWalletExists if
+// the user already has one.
+func (r *WalletRepository) AddWallet(ctx context.Context, wallet *Wallet) (*Wallet, error) {
+	result, err := r.collection.InsertOne(ctx, wallet)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrWalletExists
+		}
+		return nil, err
+	}
+	generatedID, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return nil, errors.New("failed to convert InsertedID to ObjectID")
+	}
+	wallet.Id = generatedID
+	return wallet, nil
+}
+
 func (r *WalletRepository) UpdateWallet(ctx context.Context, wallet *Wallet) (*Wallet, error) {
 	filter := bson.M{"user_id": wallet.UserId}
 	update := bson.M{"$set": wallet}
@@ -71,3 +245,16 @@ func (r *WalletRepository) UpdateWallet(ctx context.Context, wallet *Wallet) (*W
 
 	return wallet, nil
 }
+
+func (r *WalletRepository) AddTokenExpiration(ctx context.Context, expiration *TokenExpiration) (*TokenExpiration, error) {
+	result, err := r.expirationCollection.InsertOne(ctx, expiration)
+	if err != nil {
+		return nil, err
+	}
+	generatedID, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return nil, errors.New("failed to convert InsertedID to ObjectID")
+	}
+	expiration.Id = generatedID
+	return expiration, nil
+}
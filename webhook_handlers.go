@@ -0,0 +1,364 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const webhookDeliveryLogLimit = 100
+
+// validateWebhook checks the endpoint URL, the event types and the partner.
+func validateWebhook(c *gin.Context, webhook *Webhook, partnerRepo *PartnerRepository) (int, error) {
+	endpoint, err := url.Parse(webhook.Url)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return http.StatusBadRequest, errors.New("url must be an absolute http or https URL")
+	}
+	if len(webhook.EventTypes) == 0 {
+		return http.StatusBadRequest, errors.New("at least one event type is required")
+	}
+	for _, eventType := range webhook.EventTypes {
+		if !slices.Contains(webhookEventTypes, eventType) {
+			return http.StatusBadRequest, fmt.Errorf("unknown event type %q", eventType)
+		}
+	}
+	if webhook.PartnerId != nil {
+		if _, err := partnerRepo.GetPartnerByID(c.Request.Context(), *webhook.PartnerId); err != nil {
+			if err == ErrPartnerNotFound {
+				return http.StatusBadRequest, err
+			}
+			return http.StatusInternalServerError, err
+		}
+	}
+	return http.StatusOK, nil
+}
+
+// withoutSecret hides the signing secret, which is only shown on creation
+// and never written to the audit log.
+func withoutSecret(webhook Webhook) Webhook {
+	webhook.Secret = ""
+	return webhook
+}
+
+// @Summary Get webhooks
+// @Description Retrieves all webhook subscriptions. Secrets are not included.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Success 200 {array} Webhook "List of webhooks"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /webhooks [get]
+func getWebhooks(c *gin.Context, repo *WebhookRepository) {
+	webhooks, err := repo.GetAllWebhooks(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range webhooks {
+		webhooks[i] = withoutSecret(webhooks[i])
+	}
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// @Summary Get a single webhook
+// @Description Retrieves a webhook subscription by its ID. The secret is not included.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} Webhook "Single webhook"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 404 {object} ErrorResponse "Webhook not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /webhooks/{id} [get]
+func getWebhook(c *gin.Context, repo *WebhookRepository) {
+	webhook, ok := loadWebhook(c, repo)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, withoutSecret(*webhook))
+}
+
+// @Summary Add a webhook
+// @Description Subscribes a URL to event types. Deliveries are signed with HMAC-SHA256 over "<X-Webhook-Timestamp>.<body>" in the X-Webhook-Signature header. A secret is generated if none is given and is only returned in this response.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body Webhook true "Webhook to add"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 201 {object} Webhook "Webhook created, including its secret"
+// @Failure 400 {object} ErrorResponse "Invalid webhook"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /webhooks [post]
+func addWebhook(c *gin.Context, repo *WebhookRepository, partnerRepo *PartnerRepository) {
+	var webhook Webhook
+	if err := c.ShouldBindJSON(&webhook); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if status, err := validateWebhook(c, &webhook, partnerRepo); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	if webhook.Secret == "" {
+		secret, err := generateWebhookSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		webhook.Secret = secret
+	}
+	webhook.Id = primitive.NilObjectID
+	webhook.Enabled = true
+	webhook.ConsecutiveFailures = 0
+	webhook.DisabledAt = nil
+	webhook.DisabledReason = ""
+	webhook.CreatedAt = time.Now()
+
+	savedWebhook, err := repo.AddWebhook(c.Request.Context(), &webhook)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	auditAfter := withoutSecret(*savedWebhook)
+	recordAuditChange(c, "webhooks", savedWebhook.Id, nil, &auditAfter)
+	c.JSON(http.StatusCreated, savedWebhook)
+}
+
+// @Summary Update a webhook
+// @Description Updates the URL, event types, partner or enabled flag of a webhook. An empty secret keeps the current one. Enabling a disabled webhook resets its failure count.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param webhook body Webhook true "Updated webhook"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 200 {object} Webhook "Webhook updated successfully"
+// @Failure 400 {object} ErrorResponse "Invalid ID or webhook"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 404 {object} ErrorResponse "Webhook not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /webhooks/{id} [put]
+func updateWebhook(c *gin.Context, repo *WebhookRepository, partnerRepo *PartnerRepository) {
+	existing, ok := loadWebhook(c, repo)
+	if !ok {
+		return
+	}
+
+	var webhook Webhook
+	if err := c.ShouldBindJSON(&webhook); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if status, err := validateWebhook(c, &webhook, partnerRepo); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook.Id = existing.Id
+	webhook.CreatedAt = existing.CreatedAt
+	if webhook.Secret == "" {
+		webhook.Secret = existing.Secret
+	}
+	if webhook.Enabled {
+		webhook.ConsecutiveFailures = 0
+		webhook.DisabledAt = nil
+		webhook.DisabledReason = ""
+	} else {
+		webhook.ConsecutiveFailures = existing.ConsecutiveFailures
+		webhook.DisabledAt = existing.DisabledAt
+		webhook.DisabledReason = existing.DisabledReason
+		if existing.Enabled {
+			now := time.Now()
+			webhook.DisabledAt = &now
+			webhook.DisabledReason = "disabled by an administrator"
+		}
+	}
+
+	savedWebhook, err := repo.UpdateWebhook(c.Request.Context(), &webhook)
+	if err != nil {
+		if err == ErrWebhookNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	auditBefore, auditAfter := withoutSecret(*existing), withoutSecret(*savedWebhook)
+	recordAuditChange(c, "webhooks", existing.Id, &auditBefore, &auditAfter)
+	c.JSON(http.StatusOK, auditAfter)
+}
+
+// @Summary Delete a webhook
+// @Description Deletes a webhook subscription and its delivery log
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 200 {object} object "Webhook deleted successfully"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 404 {object} ErrorResponse "Webhook not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /webhooks/{id} [delete]
+func deleteWebhook(c *gin.Context, repo *WebhookRepository) {
+	existing, ok := loadWebhook(c, repo)
+	if !ok {
+		return
+	}
+
+	if err := repo.DeleteWebhook(c.Request.Context(), existing.Id); err != nil {
+		if err == ErrWebhookNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	auditBefore := withoutSecret(*existing)
+	recordAuditChange(c, "webhooks", existing.Id, &auditBefore, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// @Summary Get webhook deliveries
+// @Description Lists the most recent deliveries of a webhook, newest first
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param status query string false "Only deliveries with this status" Enums(pending, succeeded, failed)
+// @Success 200 {array} WebhookDelivery "Delivery log"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 404 {object} ErrorResponse "Webhook not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /webhooks/{id}/deliveries [get]
+func getWebhookDeliveries(c *gin.Context, repo *WebhookRepository) {
+	webhook, ok := loadWebhook(c, repo)
+	if !ok {
+		return
+	}
+
+	deliveries, err := repo.GetDeliveries(c.Request.Context(), webhook.Id, c.Query("status"), webhookDeliveryLogLimit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// @Summary Replay a webhook delivery
+// @Description Queues a logged delivery to be sent again with the same body and a fresh set of attempts
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param delivery_id path string true "Delivery ID"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 200 {object} WebhookDelivery "Delivery queued again"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 404 {object} ErrorResponse "Webhook or delivery not found"
+// @Failure 409 {object} ErrorResponse "Webhook is disabled"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /webhooks/{id}/deliveries/{delivery_id}/replay [post]
+func replayWebhookDelivery(c *gin.Context, repo *WebhookRepository) {
+	ctx := c.Request.Context()
+	webhook, ok := loadWebhook(c, repo)
+	if !ok {
+		return
+	}
+	if !webhook.Enabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "webhook is disabled"})
+		return
+	}
+	deliveryId, err := primitive.ObjectIDFromHex(c.Param("delivery_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	delivery, err := repo.GetDeliveryByID(ctx, webhook.Id, deliveryId)
+	if err != nil {
+		if err == ErrWebhookDeliveryNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	delivery.Status = DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	delivery.DeliveredAt = nil
+	delivery.LastError = ""
+	delivery.LastStatusCode = 0
+	if err := repo.UpdateDelivery(ctx, delivery); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, delivery)
+}
+
+// @Summary Ping a webhook
+// @Description Sends a signed WebhookPing event to the webhook right away and returns the outcome. Pings are logged but never retried and do not count towards disabling the webhook.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param X-Api-Key header string true "API key of a calling backend"
+// @Success 200 {object} WebhookDelivery "Ping delivery"
+// @Failure 400 {object} ErrorResponse "Invalid ID format"
+// @Failure 401 {object} ErrorResponse "Missing or invalid API key"
+// @Failure 403 {object} ErrorResponse "API key lacks the required scope"
+// @Failure 404 {object} ErrorResponse "Webhook not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /webhooks/{id}/ping [post]
+func pingWebhook(c *gin.Context, repo *WebhookRepository, deliverer *WebhookDeliverer) {
+	webhook, ok := loadWebhook(c, repo)
+	if !ok {
+		return
+	}
+
+	delivery, err := deliverer.Ping(c.Request.Context(), webhook)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, delivery)
+}
+
+// loadWebhook reads the webhook named by the id path parameter and writes
+// the error response if that fails.
+func loadWebhook(c *gin.Context, repo *WebhookRepository) (*Webhook, bool) {
+	webhookId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	webhook, err := repo.GetWebhookByID(c.Request.Context(), webhookId)
+	if err != nil {
+		if err == ErrWebhookNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return webhook, true
+}
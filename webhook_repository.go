@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+var ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook is a subscription of an HTTP endpoint to event types. Webhooks
+// with a partner only receive events about that partner's benefits.
+type Webhook struct {
+	Id                  primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	PartnerId           *primitive.ObjectID `json:"partnerId,omitempty" bson:"partnerId,omitempty"`
+	Url                 string              `json:"url" bson:"url" example:"https://partner.example.com/hooks/cityboost"`
+	EventTypes          []string            `json:"eventTypes" bson:"eventTypes"`
+	Secret              string              `json:"secret,omitempty" bson:"secret"`
+	Enabled             bool                `json:"enabled" bson:"enabled"`
+	ConsecutiveFailures int                 `json:"consecutiveFailures" bson:"consecutiveFailures"`
+	DisabledAt          *time.Time          `json:"disabledAt,omitempty" bson:"disabledAt,omitempty"`
+	DisabledReason      string              `json:"disabledReason,omitempty" bson:"disabledReason,omitempty"`
+	CreatedAt           time.Time           `json:"createdAt" bson:"createdAt"`
+}
+
+// WebhookDelivery is one event sent, or to be sent, to a webhook. The body
+// is kept so replays send exactly the same payload.
+type WebhookDelivery struct {
+	Id             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	WebhookId      primitive.ObjectID `json:"webhookId" bson:"webhookId"`
+	EventId        primitive.ObjectID `json:"eventId" bson:"eventId"`
+	EventType      string             `json:"eventType" bson:"eventType"`
+	Body           string             `json:"body" bson:"body"`
+	Status         string             `json:"status" bson:"status" enums:"pending,succeeded,failed"`
+	Attempts       int                `json:"attempts" bson:"attempts"`
+	NextAttemptAt  time.Time          `json:"nextAttemptAt" bson:"nextAttemptAt"`
+	LastStatusCode int                `json:"lastStatusCode,omitempty" bson:"lastStatusCode,omitempty"`
+	LastError      string             `json:"lastError,omitempty" bson:"lastError,omitempty"`
+	CreatedAt      time.Time          `json:"createdAt" bson:"createdAt"`
+	DeliveredAt    *time.Time         `json:"deliveredAt,omitempty" bson:"deliveredAt,omitempty"`
+}
+
+type WebhookRepository struct {
+	collection         *mongo.Collection
+	deliveryCollection *mongo.Collection
+}
+
+func NewWebhookRepository(collection *mongo.Collection, deliveryCollection *mongo.Collection) (*WebhookRepository, error) {
+	_, err := deliveryCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		// An event is delivered to a webhook once even if the outbox retries it
+		{
+			Keys:    bson.D{{Key: "webhookId", Value: 1}, {Key: "eventId", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "nextAttemptAt", Value: 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebhookRepository{
+		collection:         collection,
+		deliveryCollection: deliveryCollection,
+	}, nil
+}
+
+func (r *WebhookRepository) GetAllWebhooks(ctx context.Context) ([]Webhook, error) {
+	return r.getWebhooks(ctx, bson.M{})
+}
+
+// GetSubscribedWebhooks returns the enabled webhooks subscribed to the event type.
+func (r *WebhookRepository) GetSubscribedWebhooks(ctx context.Context, eventType string) ([]Webhook, error) {
+	return r.getWebhooks(ctx, bson.M{"enabled": true, "eventTypes": eventType})
+}
+
+func (r *WebhookRepository) getWebhooks(ctx context.Context, filter bson.M) ([]Webhook, error) {
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	webhooks := []Webhook{}
+	if err := cursor.All(ctx, &webhooks); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (r *WebhookRepository) GetWebhookByID(ctx context.Context, id primitive.ObjectID) (*Webhook, error) {
+	var webhook Webhook
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&webhook)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+func (r *WebhookRepository) AddWebhook(ctx context.Context, webhook *Webhook) (*Webhook, error) {
+	result, err := r.collection.InsertOne(ctx, webhook)
+	if err != nil {
+		return nil, err
+	}
+	generatedID, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return nil, errors.New("failed to convert InsertedID to ObjectID")
+	}
+	webhook.Id = generatedID
+	return webhook, nil
+}
+
+func (r *WebhookRepository) UpdateWebhook(ctx context.Context, webhook *Webhook) (*Webhook, error) {
+	result, err := r.collection.ReplaceOne(ctx, bson.M{"_id": webhook.Id}, webhook)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook removes the webhook together with its delivery log.
+func (r *WebhookRepository) DeleteWebhook(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrWebhookNotFound
+	}
+	_, err = r.deliveryCollection.DeleteMany(ctx, bson.M{"webhookId": id})
+	return err
+}
+
+// RecordSuccess resets the failure streak of the webhook.
+func (r *WebhookRepository) RecordSuccess(ctx context.Context, id primitive.ObjectID) error {
+	update := bson.M{"$set": bson.M{"consecutiveFailures": 0}}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// RecordFailure extends the failure streak of the webhook and disables it
+// once the streak reaches disableAfter. It reports whether the webhook was
+// disabled by this call.
+func (r *WebhookRepository) RecordFailure(ctx context.Context, id primitive.ObjectID, disableAfter int, now time.Time) (bool, error) {
+	var webhook Webhook
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"consecutiveFailures": 1}}, opts).Decode(&webhook)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return false, ErrWebhookNotFound
+		}
+		return false, err
+	}
+	if !webhook.Enabled || webhook.ConsecutiveFailures < disableAfter {
+		return false, nil
+	}
+
+	filter := bson.M{"_id": id, "enabled": true}
+	update := bson.M{"$set": bson.M{
+		"enabled":        false,
+		"disabledAt":     now,
+		"disabledReason": "too many consecutive failed deliveries",
+	}}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// AddDelivery queues a delivery. Queuing the same event for the same
+// webhook again is a no-op.
+func (r *WebhookRepository) AddDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	_, err := r.deliveryCollection.InsertOne(ctx, delivery)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+func (r *WebhookRepository) GetDeliveryByID(ctx context.Context, webhookId primitive.ObjectID, id primitive.ObjectID) (*WebhookDelivery, error) {
+	var delivery WebhookDelivery
+	err := r.deliveryCollection.FindOne(ctx, bson.M{"_id": id, "webhookId": webhookId}).Decode(&delivery)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrWebhookDeliveryNotFound
+		}
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+// GetDeliveries returns the most recent deliveries of a webhook, newest first.
+func (r *WebhookRepository) GetDeliveries(ctx context.Context, webhookId primitive.ObjectID, status string, limit int64) ([]WebhookDelivery, error) {
+	filter := bson.M{"webhookId": webhookId}
+	if status != "" {
+		filter["status"] = status
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(limit)
+	return r.getDeliveries(ctx, filter, opts)
+}
+
+// GetDueDeliveries returns pending deliveries whose next attempt is due, oldest first.
+func (r *WebhookRepository) GetDueDeliveries(ctx context.Context, now time.Time, limit int64) ([]WebhookDelivery, error) {
+	filter := bson.M{"status": DeliveryPending, "nextAttemptAt": bson.M{"$lte": now}}
+	opts := options.Find().SetSort(bson.D{{Key: "nextAttemptAt", Value: 1}}).SetLimit(limit)
+	return r.getDeliveries(ctx, filter, opts)
+}
+
+func (r *WebhookRepository) getDeliveries(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]WebhookDelivery, error) {
+	cursor, err := r.deliveryCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	deliveries := []WebhookDelivery{}
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// UpdateDelivery stores the outcome of a delivery attempt.
+func (r *WebhookRepository) UpdateDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	_, err := r.deliveryCollection.ReplaceOne(ctx, bson.M{"_id": delivery.Id}, delivery)
+	return err
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	webhookDelivererLock = "webhook-deliverer"
+	webhookBatchSize     = 100
+	webhookMaxAttempts   = 8
+	webhookRetryBase     = 30 * time.Second
+	webhookRetryMax      = 6 * time.Hour
+	// A webhook is disabled after this many failed attempts in a row,
+	// counted across all of its deliveries.
+	webhookDisableAfter = 20
+	webhookSecretPrefix = "whsec_"
+	// The lease is renewed before every delivery, so it only has to outlast
+	// a single request to a webhook endpoint.
+	webhookLeaseTTL = time.Minute
+)
+
+// EventWebhookPing is only sent by the ping endpoint to test a webhook.
+const EventWebhookPing = "WebhookPing"
+
+// webhookEventTypes are the events webhooks can subscribe to.
+var webhookEventTypes = []string{
+	EventBenefitPurchased,
+	EventBenefitRedeemed,
+	EventBenefitExpired,
+	EventOwnedBenefitExpired,
+	EventTokensGranted,
+	EventTokensExpired,
+}
+
+func generateWebhookSecret() (string, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return webhookSecretPrefix + hex.EncodeToString(raw), nil
+}
+
+// signWebhook signs "<timestamp>.<body>" with HMAC-SHA256 so receivers can
+// check both the sender and the age of a delivery.
+func signWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// eventPartnerId returns the partner an event is about, if any.
+func eventPartnerId(event Event) *primitive.ObjectID {
+	data, err := json.Marshal(event.Payload)
+	if err != nil {
+		return nil
+	}
+	var payload struct {
+		PartnerId *primitive.ObjectID `json:"partnerId"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil
+	}
+	return payload.PartnerId
+}
+
+func newWebhookDelivery(webhookId primitive.ObjectID, event Event, now time.Time) (*WebhookDelivery, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	return &WebhookDelivery{
+		Id:            primitive.NewObjectID(),
+		WebhookId:     webhookId,
+		EventId:       event.Id,
+		EventType:     event.Type,
+		Body:          string(body),
+		Status:        DeliveryPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}, nil
+}
+
+// WebhookSink is an outbox sink that queues a delivery for every webhook
+// subscribed to the event.
+type WebhookSink struct {
+	repo *WebhookRepository
+}
+
+func NewWebhookSink(repo *WebhookRepository) *WebhookSink {
+	return &WebhookSink{repo: repo}
+}
+
+func (s *WebhookSink) Publish(ctx context.Context, event Event) error {
+	webhooks, err := s.repo.GetSubscribedWebhooks(ctx, event.Type)
+	if err != nil {
+		return err
+	}
+
+	partnerId := eventPartnerId(event)
+	now := time.Now()
+	for _, webhook := range webhooks {
+		if webhook.PartnerId != nil && (partnerId == nil || *partnerId != *webhook.PartnerId) {
+			continue
+		}
+		delivery, err := newWebhookDelivery(webhook.Id, event, now)
+		if err != nil {
+			return err
+		}
+		if err := s.repo.AddDelivery(ctx, delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WebhookDeliverer sends queued webhook deliveries, retrying failed ones
+// with exponential backoff. Only the replica holding the lock sends.
+type WebhookDeliverer struct {
+	repo     *WebhookRepository
+	lock     *MongoLock
+	client   *http.Client
+	interval time.Duration
+}
+
+func NewWebhookDeliverer(repo *WebhookRepository, lock *MongoLock, client *http.Client, interval time.Duration) *WebhookDeliverer {
+	return &WebhookDeliverer{
+		repo:     repo,
+		lock:     lock,
+		client:   client,
+		interval: interval,
+	}
+}
+
+// Run delivers once immediately and then on every tick until ctx is
+// cancelled.
+func (d *WebhookDeliverer) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		d.runOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *WebhookDeliverer) runOnce(ctx context.Context) {
+	acquired, err := d.lock.TryAcquire(ctx, webhookDelivererLock, webhookLeaseTTL)
+	if err != nil {
+		log.Printf("webhook deliverer failed to acquire lock: %v", err)
+		return
+	}
+	if !acquired {
+		return
+	}
+
+	if err := d.Deliver(ctx, time.Now()); err != nil {
+		log.Printf("webhook delivery failed: %v", err)
+	}
+}
+
+// Deliver sends the deliveries that are due at now. It stops as soon as the
+// lease cannot be renewed so two replicas never send the same delivery.
+func (d *WebhookDeliverer) Deliver(ctx context.Context, now time.Time) error {
+	deliveries, err := d.repo.GetDueDeliveries(ctx, now, webhookBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for i := range deliveries {
+		if err := d.lock.Renew(ctx, webhookDelivererLock, webhookLeaseTTL); err != nil {
+			return err
+		}
+		if err := d.attempt(ctx, &deliveries[i], now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *WebhookDeliverer) attempt(ctx context.Context, delivery *WebhookDelivery, now time.Time) error {
+	webhook, err := d.repo.GetWebhookByID(ctx, delivery.WebhookId)
+	if err != nil && err != ErrWebhookNotFound {
+		return err
+	}
+	if webhook == nil || !webhook.Enabled {
+		// Disabled webhooks get nothing; their deliveries can be replayed later
+		delivery.Status = DeliveryFailed
+		delivery.LastError = "webhook is disabled"
+		return d.repo.UpdateDelivery(ctx, delivery)
+	}
+
+	statusCode, sendErr := d.send(ctx, webhook, delivery, now)
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	if sendErr == nil {
+		delivery.Status = DeliverySucceeded
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		if err := d.repo.RecordSuccess(ctx, webhook.Id); err != nil {
+			return err
+		}
+		return d.repo.UpdateDelivery(ctx, delivery)
+	}
+
+	delivery.LastError = sendErr.Error()
+	if delivery.Attempts >= webhookMaxAttempts {
+		delivery.Status = DeliveryFailed
+	} else {
+		delivery.NextAttemptAt = now.Add(retryDelay(delivery.Attempts, webhookRetryBase, webhookRetryMax))
+	}
+	disabled, err := d.repo.RecordFailure(ctx, webhook.Id, webhookDisableAfter, now)
+	if err != nil && err != ErrWebhookNotFound {
+		return err
+	}
+	if disabled {
+		log.Printf("disabled webhook %s after %d failed deliveries in a row", webhook.Id.Hex(), webhookDisableAfter)
+	}
+	return d.repo.UpdateDelivery(ctx, delivery)
+}
+
+// send posts the delivery body to the webhook. Any 2xx response counts as
+// delivered.
+func (d *WebhookDeliverer) send(ctx context.Context, webhook *Webhook, delivery *WebhookDelivery, now time.Time) (int, error) {
+	body := []byte(delivery.Body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "cityboost-webhooks/1.0")
+	req.Header.Set("X-Webhook-Id", delivery.Id.Hex())
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", signWebhook(webhook.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Ping sends a test event to the webhook right away, without retries and
+// without counting towards disabling it, and logs the delivery.
+func (d *WebhookDeliverer) Ping(ctx context.Context, webhook *Webhook) (*WebhookDelivery, error) {
+	now := time.Now()
+	event := NewEvent(EventWebhookPing, webhook.Id, map[string]any{"webhookId": webhook.Id})
+	delivery, err := newWebhookDelivery(webhook.Id, event, now)
+	if err != nil {
+		return nil, err
+	}
+
+	statusCode, sendErr := d.send(ctx, webhook, delivery, now)
+	delivery.Attempts = 1
+	delivery.LastStatusCode = statusCode
+	if sendErr != nil {
+		delivery.Status = DeliveryFailed
+		delivery.LastError = sendErr.Error()
+	} else {
+		delivery.Status = DeliverySucceeded
+		delivery.DeliveredAt = &now
+	}
+	if err := d.repo.AddDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}